@@ -0,0 +1,77 @@
+package ast
+
+import (
+	"fmt"
+	"lo/parseerror"
+	"lo/token"
+)
+
+// LoxClass is the runtime representation of a class declaration. Calling
+// it creates a new LoxInstance
+type LoxClass struct {
+	Name       string
+	Superclass *LoxClass
+	Methods    map[string]*LoxFunction
+}
+
+// FindMethod looks up a method by name on the class and then up its
+// superclass chain
+func (c *LoxClass) FindMethod(name string) *LoxFunction {
+	if method, found := c.Methods[name]; found {
+		return method
+	}
+	if c.Superclass != nil {
+		return c.Superclass.FindMethod(name)
+	}
+	return nil
+}
+
+// Arity is the number of parameters the init method expects
+func (c *LoxClass) Arity() int {
+	initializer := c.FindMethod("init")
+	if initializer == nil {
+		return 0
+	}
+	return initializer.Arity()
+}
+
+// Call creates a new instance and runs the init method on it if the class
+// has one
+func (c *LoxClass) Call(i *Interpreter, arguments []interface{}) interface{} {
+	instance := &LoxInstance{Class: c, Fields: make(map[string]interface{})}
+	if initializer := c.FindMethod("init"); initializer != nil {
+		initializer.Bind(instance).Call(i, arguments)
+	}
+	return instance
+}
+
+func (c *LoxClass) String() string {
+	return c.Name
+}
+
+// LoxInstance is an object created by calling a LoxClass
+type LoxInstance struct {
+	Class  *LoxClass
+	Fields map[string]interface{}
+}
+
+// Get returns the field with the given name or a method bound to the
+// instance. Fields shadow methods
+func (l *LoxInstance) Get(name token.Token) (interface{}, error) {
+	if value, found := l.Fields[name.Lexeme]; found {
+		return value, nil
+	}
+	if method := l.Class.FindMethod(name.Lexeme); method != nil {
+		return method.Bind(l), nil
+	}
+	return nil, &parseerror.RunTimeError{Token: name, Message: fmt.Sprintf("Undefined property '%s'.", name.Lexeme)}
+}
+
+// Set creates or updates a field on the instance
+func (l *LoxInstance) Set(name token.Token, value interface{}) {
+	l.Fields[name.Lexeme] = value
+}
+
+func (l *LoxInstance) String() string {
+	return fmt.Sprintf("%s instance", l.Class.Name)
+}
@@ -8,18 +8,38 @@ import (
 
 // Expr is the base of all expressions
 type Expr interface {
-	Accept(i Interpreter) interface{}
+	Accept(v ExprVisitor) interface{}
+	Span() token.Span
+}
+
+// ExprVisitor is implemented by the passes that walk expressions i.e. the
+// Interpreter and the resolver
+type ExprVisitor interface {
+	VisitAssignExpression(e *AssignExpr) interface{}
+	VisitBinaryExpression(e *BinaryExpr) interface{}
+	VisitCallExpression(e *CallExpr) interface{}
+	VisitConditionalExpression(e *ConditionalExpr) interface{}
+	VisitGetExpression(e *GetExpr) interface{}
+	VisitGroupExpression(e *GroupExpr) interface{}
+	VisitLiteralExpression(e *LiteralExpr) interface{}
+	VisitLogicalExpression(e *LogicalExpr) interface{}
+	VisitSetExpression(e *SetExpr) interface{}
+	VisitSuperExpression(e *SuperExpr) interface{}
+	VisitThisExpression(e *ThisExpr) interface{}
+	VisitUnaryExpression(e *UnaryExpr) interface{}
+	VisitVariableExpression(e *VariableExpr) interface{}
 }
 
 // AssignExpr defines = operation
 type AssignExpr struct {
+	Node
 	Name  token.Token
 	Value Expr
 }
 
 // Accept ...
-func (t *AssignExpr) Accept(i Interpreter) interface{} {
-	return i.VisitAssignExpression(t)
+func (t *AssignExpr) Accept(v ExprVisitor) interface{} {
+	return v.VisitAssignExpression(t)
 }
 
 func (t *AssignExpr) String() string {
@@ -32,14 +52,15 @@ func (t *AssignExpr) String() string {
 
 // BinaryExpr ...
 type BinaryExpr struct {
+	Node
 	Left     Expr
 	Operator token.Token
 	Right    Expr
 }
 
 // Accept ...
-func (t *BinaryExpr) Accept(i Interpreter) interface{} {
-	return i.VisitBinaryExpression(t)
+func (t *BinaryExpr) Accept(v ExprVisitor) interface{} {
+	return v.VisitBinaryExpression(t)
 }
 
 func (t *BinaryExpr) String() string {
@@ -56,14 +77,15 @@ func (t *BinaryExpr) String() string {
 
 // CallExpr ...
 type CallExpr struct {
+	Node
 	Callee    Expr
 	Paren     token.Token
 	Arguments []Expr
 }
 
 // Accept ...
-func (c *CallExpr) Accept(i Interpreter) interface{} {
-	return i.VisitCallExpression(c)
+func (c *CallExpr) Accept(v ExprVisitor) interface{} {
+	return v.VisitCallExpression(c)
 }
 
 // String prints the call operator
@@ -82,15 +104,42 @@ func (c *CallExpr) String() string {
 	return sb.String()
 }
 
+// ConditionalExpr defines the cond ? a : b operation
+type ConditionalExpr struct {
+	Node
+	Condition  Expr
+	ThenBranch Expr
+	ElseBranch Expr
+}
+
+// Accept ...
+func (c *ConditionalExpr) Accept(v ExprVisitor) interface{} {
+	return v.VisitConditionalExpression(c)
+}
+
+// String pretty prints the conditional operator
+func (c *ConditionalExpr) String() string {
+	var sb strings.Builder
+	sb.WriteString("(?: ")
+	sb.WriteString(fmt.Sprint(c.Condition))
+	sb.WriteString(" ")
+	sb.WriteString(fmt.Sprint(c.ThenBranch))
+	sb.WriteString(" ")
+	sb.WriteString(fmt.Sprint(c.ElseBranch))
+	sb.WriteString(")")
+	return sb.String()
+}
+
 // GetExpr defines a property access functionality
 type GetExpr struct {
+	Node
 	Expression Expr
 	Name       token.Token
 }
 
 // Accept ...
-func (g *GetExpr) Accept(i Interpreter) interface{} {
-	return i.VisitGetExpression(g)
+func (g *GetExpr) Accept(v ExprVisitor) interface{} {
+	return v.VisitGetExpression(g)
 }
 
 // String pretty prints the class
@@ -108,12 +157,13 @@ func (g *GetExpr) String() string {
 
 // GroupExpr defines a property access functionality
 type GroupExpr struct {
+	Node
 	Expression Expr
 }
 
 // Accept ...
-func (t *GroupExpr) Accept(i Interpreter) interface{} {
-	return i.VisitGroupExpression(t)
+func (t *GroupExpr) Accept(v ExprVisitor) interface{} {
+	return v.VisitGroupExpression(t)
 }
 
 func (t *GroupExpr) String() string {
@@ -126,12 +176,13 @@ func (t *GroupExpr) String() string {
 
 // LiteralExpr defines a property access functionality
 type LiteralExpr struct {
+	Node
 	Object interface{}
 }
 
 // Accept ...
-func (t *LiteralExpr) Accept(i Interpreter) interface{} {
-	return i.VisitLiteralExpression(t)
+func (t *LiteralExpr) Accept(v ExprVisitor) interface{} {
+	return v.VisitLiteralExpression(t)
 }
 
 func (t *LiteralExpr) String() string {
@@ -142,14 +193,15 @@ func (t *LiteralExpr) String() string {
 
 // LogicalExpr defines a property access functionality
 type LogicalExpr struct {
+	Node
 	Left     Expr
 	Operator token.Token
 	Right    Expr
 }
 
 // Accept ...
-func (l *LogicalExpr) Accept(i Interpreter) interface{} {
-	return i.VisitLogicalExpression(l)
+func (l *LogicalExpr) Accept(v ExprVisitor) interface{} {
+	return v.VisitLogicalExpression(l)
 }
 
 // String pretty prints the unary operator
@@ -167,14 +219,15 @@ func (l *LogicalExpr) String() string {
 
 // SetExpr defines a property access functionality
 type SetExpr struct {
+	Node
 	Object Expr
 	Name   token.Token
 	Value  Expr
 }
 
 // Accept ...
-func (s *SetExpr) Accept(i Interpreter) interface{} {
-	return i.VisitSetExpression(s)
+func (s *SetExpr) Accept(v ExprVisitor) interface{} {
+	return v.VisitSetExpression(s)
 }
 
 // String pretty prints the setter
@@ -192,14 +245,36 @@ func (s *SetExpr) String() string {
 	return sb.String()
 }
 
+// SuperExpr looks up a method on the superclass of the enclosing class
+type SuperExpr struct {
+	Node
+	Keyword token.Token
+	Method  token.Token
+}
+
+// Accept ...
+func (s *SuperExpr) Accept(v ExprVisitor) interface{} {
+	return v.VisitSuperExpression(s)
+}
+
+// String pretty prints the superclass method access
+func (s *SuperExpr) String() string {
+	var sb strings.Builder
+	sb.WriteString("(super ")
+	sb.WriteString(s.Method.Lexeme)
+	sb.WriteString(")")
+	return sb.String()
+}
+
 // ThisExpr defines a property access functionality
 type ThisExpr struct {
+	Node
 	Keyword token.Token
 }
 
 // Accept ...
-func (t *ThisExpr) Accept(i Interpreter) interface{} {
-	return i.VisitThisExpression(t)
+func (t *ThisExpr) Accept(v ExprVisitor) interface{} {
+	return v.VisitThisExpression(t)
 }
 
 func (t *ThisExpr) String() string {
@@ -208,13 +283,14 @@ func (t *ThisExpr) String() string {
 
 // UnaryExpr defines a property access functionality
 type UnaryExpr struct {
+	Node
 	Operator token.Token
 	Right    Expr
 }
 
 // Accept ...
-func (t *UnaryExpr) Accept(i Interpreter) interface{} {
-	return i.VisitUnaryExpression(t)
+func (t *UnaryExpr) Accept(v ExprVisitor) interface{} {
+	return v.VisitUnaryExpression(t)
 }
 
 func (t *UnaryExpr) String() string {
@@ -226,12 +302,13 @@ func (t *UnaryExpr) String() string {
 
 // VariableExpr defines a property access functionality
 type VariableExpr struct {
+	Node
 	Name token.Token
 }
 
 // Accept ...
-func (t *VariableExpr) Accept(i Interpreter) interface{} {
-	return i.VisitVariableExpression(t)
+func (t *VariableExpr) Accept(v ExprVisitor) interface{} {
+	return v.VisitVariableExpression(t)
 }
 
 func (t *VariableExpr) String() string {
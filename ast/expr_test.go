@@ -8,11 +8,12 @@ import (
 
 func TestExpr(t *testing.T) {
 	left := &UnaryExpr{
-		token.Token{Type: token.MINUS, Lexeme: "-", Literal: nil, Line: 1}, &LiteralExpr{123},
+		Operator: token.Token{Type: token.MINUS, Lexeme: "-", Literal: nil, Line: 1},
+		Right:    &LiteralExpr{Object: 123},
 	}
-	right := &GroupExpr{&LiteralExpr{245}}
+	right := &GroupExpr{Expression: &LiteralExpr{Object: 245}}
 	operator := token.Token{Type: token.PLUS, Lexeme: "+", Literal: nil, Line: 1}
-	expression := &BinaryExpr{left, operator, right}
+	expression := &BinaryExpr{Left: left, Operator: operator, Right: right}
 	expected := "(+ -123 (245))"
 
 	if fmt.Sprintf("%s", expression) != expected {
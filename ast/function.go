@@ -0,0 +1,70 @@
+package ast
+
+import (
+	"fmt"
+	"lo/environment"
+)
+
+// LoxCallable is any Lox value that can be called like a function
+type LoxCallable interface {
+	Arity() int
+	Call(i *Interpreter, arguments []interface{}) interface{}
+}
+
+// Return carries the value of a return statement up the Go stack to the
+// function call that is being executed
+type Return struct {
+	Value interface{}
+}
+
+// LoxFunction is the runtime representation of a function declaration.
+// Closure is the environment the function was declared in
+type LoxFunction struct {
+	Declaration   *FunctionStmt
+	Closure       *environment.Environment
+	IsInitializer bool
+}
+
+// Arity is the number of parameters the function expects
+func (f *LoxFunction) Arity() int {
+	return len(f.Declaration.Params)
+}
+
+// Call binds the arguments to the parameters in a new environment nested
+// in the closure and executes the function body in it
+func (f *LoxFunction) Call(i *Interpreter, arguments []interface{}) (value interface{}) {
+	env := environment.NewEnvironment(f.Closure)
+	for idx, param := range f.Declaration.Params {
+		env.Define(param.Lexeme, arguments[idx])
+	}
+
+	defer func() {
+		if r := recover(); r != nil {
+			ret, ok := r.(*Return)
+			if !ok {
+				panic(r)
+			}
+			value = ret.Value
+			if f.IsInitializer {
+				value = f.Closure.GetAt(0, "this")
+			}
+		}
+	}()
+	i.executeBlock(f.Declaration.Body, env)
+	if f.IsInitializer {
+		return f.Closure.GetAt(0, "this")
+	}
+	return nil
+}
+
+// Bind creates a copy of the method whose closure has this bound to the
+// instance
+func (f *LoxFunction) Bind(instance *LoxInstance) *LoxFunction {
+	env := environment.NewEnvironment(f.Closure)
+	env.Define("this", instance)
+	return &LoxFunction{Declaration: f.Declaration, Closure: env, IsInitializer: f.IsInitializer}
+}
+
+func (f *LoxFunction) String() string {
+	return fmt.Sprintf("<fn %s>", f.Declaration.Name.Lexeme)
+}
@@ -5,26 +5,106 @@ import (
 	"lo/environment"
 	"lo/parseerror"
 	"lo/token"
+	"math"
 	"reflect"
 )
 
+// maxCallDepth is the most calls that can be in progress at once. Deeper
+// recursion is a runtime error rather than a crash of the Go stack
+const maxCallDepth = 1000
+
 // Interpreter ...
 type Interpreter struct {
-	Environment environment.Environment
+	Globals     *environment.Environment
+	Environment *environment.Environment
+	locals      map[Expr]int
+	diagnostics *parseerror.Diagnostics
+	// frames are the calls in progress, outermost first
+	frames []parseerror.Frame
+}
+
+// NewInterpreter creates a new interpreter that reports runtime errors to
+// diagnostics
+func NewInterpreter(diagnostics *parseerror.Diagnostics) *Interpreter {
+	env := environment.NewEnvironment(nil)
+	return &Interpreter{Globals: env, Environment: env, locals: make(map[Expr]int), diagnostics: diagnostics}
+}
+
+// Resolve records how many scopes out from expr its variable is bound.
+// Variables that are never resolved are looked up in the globals
+func (i *Interpreter) Resolve(expr Expr, depth int) {
+	i.locals[expr] = depth
+}
+
+// lookUpVariable gets the value of a variable from the scope the resolver
+// bound it to
+func (i *Interpreter) lookUpVariable(name token.Token, expr Expr) interface{} {
+	if distance, found := i.locals[expr]; found {
+		return i.Environment.GetAt(distance, name.Lexeme)
+	}
+	value, err := i.Globals.Get(name)
+	if err != nil {
+		panic(err)
+	}
+	return value
+}
+
+// Interpret executes the statements in order. It stops at the first
+// runtime error, reports it and returns it
+func (i *Interpreter) Interpret(stmts []Stmt) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			runTimeError, ok := r.(*parseerror.RunTimeError)
+			if !ok {
+				panic(r)
+			}
+			// calls are only popped when they return, so the frames still
+			// hold the stack at the point of the error
+			runTimeError.Stack = i.stackTrace()
+			i.frames = nil
+			i.diagnostics.Report(runTimeError)
+			err = runTimeError
+		}
+	}()
+	for _, stmt := range stmts {
+		i.execute(stmt)
+	}
+	return nil
+}
+
+// runTimeError stops the execution with an error at the given token. It
+// unwinds the Go stack up to Interpret which returns the error
+func (i *Interpreter) runTimeError(t token.Token, message string) {
+	panic(&parseerror.RunTimeError{Token: t, Message: message})
 }
 
-// NewInterpreter creates a new interpreter
-func NewInterpreter() *Interpreter {
-	env := environment.NewEnvironment()
-	return &Interpreter{Environment: env}
+// stackTrace copies the calls in progress, innermost first
+func (i *Interpreter) stackTrace() []parseerror.Frame {
+	if len(i.frames) == 0 {
+		return nil
+	}
+	stack := make([]parseerror.Frame, 0, len(i.frames))
+	for idx := len(i.frames) - 1; idx >= 0; idx-- {
+		stack = append(stack, i.frames[idx])
+	}
+	return stack
 }
 
-// Interpret the given expressions
-func (i Interpreter) Interpret(stmts []Stmt) {
-	// value := i.evaluate(e)
-	// fmt.Println(value)
+// execute is a helper that revisits the interpretor for statements
+func (i *Interpreter) execute(stmt Stmt) interface{} {
+	return stmt.Accept(i)
+}
+
+// executeBlock runs the statements in env and restores the current
+// environment once done
+func (i *Interpreter) executeBlock(stmts []Stmt, env *environment.Environment) {
+	previous := i.Environment
+	defer func() {
+		i.Environment = previous
+	}()
+	i.Environment = env
 	for _, stmt := range stmts {
-		i.evaluate(stmt)
+		i.execute(stmt)
 	}
 }
 
@@ -36,20 +116,24 @@ func (i *Interpreter) String() string {
 }
 
 // VisitAssignExpression ...
-func (i Interpreter) VisitAssignExpression(e *AssignExpr) interface{} {
+func (i *Interpreter) VisitAssignExpression(e *AssignExpr) interface{} {
 	value := i.evaluate(e.Value)
-	i.Environment.Assign(e.Name, value)
+	if distance, found := i.locals[e]; found {
+		i.Environment.AssignAt(distance, e.Name, value)
+	} else if err := i.Globals.Assign(e.Name, value); err != nil {
+		panic(err)
+	}
 	return value
 }
 
 // VisitBinaryExpression ...
-func (i Interpreter) VisitBinaryExpression(e *BinaryExpr) interface{} {
+func (i *Interpreter) VisitBinaryExpression(e *BinaryExpr) interface{} {
 	left := i.evaluate(e.Left)
 	right := i.evaluate(e.Right)
 
 	switch e.Operator.Type {
 	case token.MINUS:
-		i.checkOneNumberOperand(e.Operator, right)
+		i.checkTwoNumberOperands(e.Operator, left, right)
 		return left.(float64) - right.(float64)
 	case token.SLASH:
 		i.checkTwoNumberOperands(e.Operator, left, right)
@@ -57,6 +141,9 @@ func (i Interpreter) VisitBinaryExpression(e *BinaryExpr) interface{} {
 	case token.STAR:
 		i.checkTwoNumberOperands(e.Operator, left, right)
 		return left.(float64) * right.(float64)
+	case token.POWER:
+		i.checkTwoNumberOperands(e.Operator, left, right)
+		return math.Pow(left.(float64), right.(float64))
 	case token.GREATER:
 		i.checkTwoNumberOperands(e.Operator, left, right)
 		return left.(float64) > right.(float64)
@@ -74,108 +161,190 @@ func (i Interpreter) VisitBinaryExpression(e *BinaryExpr) interface{} {
 	case token.EQUALEQUAL:
 		return i.isEqual(left, right)
 	case token.PLUS:
-		typeLeft := reflect.TypeOf(left).String()
-		typeRight := reflect.TypeOf(right).String()
-		if (typeLeft == "float64" || typeLeft == "float32" || typeLeft == "int") && (typeRight == "float64" || typeRight == "float32" || typeRight == "int") {
-			return left.(float64) + right.(float64)
-		} else if typeLeft == "string" && typeRight == "string" {
-			return left.(string) + right.(string)
+		leftNumber, leftIsNumber := left.(float64)
+		rightNumber, rightIsNumber := right.(float64)
+		if leftIsNumber && rightIsNumber {
+			return leftNumber + rightNumber
 		}
-		return &parseerror.RunTimeError{
-			Token:   e.Operator,
-			Message: fmt.Sprintf("Operand %v and %v must be numbers or strings", left, right)}
+		leftString, leftIsString := left.(string)
+		rightString, rightIsString := right.(string)
+		if leftIsString && rightIsString {
+			return leftString + rightString
+		}
+		i.runTimeError(e.Operator, fmt.Sprintf("Operand %v and %v must be numbers or strings", left, right))
 	}
 	return nil
 }
 
-// checkOneNumberOperand if it's a float or int
-func (i Interpreter) checkOneNumberOperand(operator token.Token, operand interface{}) error {
-	typeOfOperand := reflect.TypeOf(operand).String()
-	if typeOfOperand == "float64" || typeOfOperand == "float32" || typeOfOperand == "int" {
-		return nil
+// checkOneNumberOperand raises a runtime error unless the operand is a
+// number
+func (i *Interpreter) checkOneNumberOperand(operator token.Token, operand interface{}) {
+	if _, ok := operand.(float64); ok {
+		return
 	}
-	return &parseerror.RunTimeError{Token: operator, Message: fmt.Sprintf("Operand %v must be a number", operand)}
+	i.runTimeError(operator, fmt.Sprintf("Operand %v must be a number", operand))
 }
 
-// checkTwoNumberOperands if they are floats or ints
-func (i Interpreter) checkTwoNumberOperands(operator token.Token, left interface{}, right interface{}) error {
-	typeOfLeftOperand := reflect.TypeOf(left).String()
-	typeOfRightOperand := reflect.TypeOf(right).String()
-	if (typeOfLeftOperand == "float64" || typeOfLeftOperand == "float32" || typeOfLeftOperand == "int") &&
-		(typeOfRightOperand == "float64" || typeOfRightOperand == "float32" || typeOfRightOperand == "int") {
-		return nil
+// checkTwoNumberOperands raises a runtime error unless both operands are
+// numbers
+func (i *Interpreter) checkTwoNumberOperands(operator token.Token, left interface{}, right interface{}) {
+	_, leftIsNumber := left.(float64)
+	_, rightIsNumber := right.(float64)
+	if leftIsNumber && rightIsNumber {
+		return
 	}
-	return &parseerror.RunTimeError{Token: operator, Message: fmt.Sprintf("Operand %v and %v must be a number", left, right)}
+	i.runTimeError(operator, fmt.Sprintf("Operand %v and %v must be a number", left, right))
 }
 
-// isEqual returns true if 2 objects are the same
-func (i Interpreter) isEqual(left interface{}, right interface{}) bool {
-	if left == nil && right == nil {
-		return true
+// isEqual returns true if 2 objects are the same. Numbers, strings,
+// booleans and nil compare by value while functions, classes and
+// instances are only equal to themselves
+func (i *Interpreter) isEqual(left interface{}, right interface{}) bool {
+	switch left.(type) {
+	case nil, float64, string, bool:
+		return left == right
+	case *LoxFunction, *LoxClass, *LoxInstance:
+		// the pointers are compared, not what they point to
+		return left == right
 	}
-	if left == nil {
-		return false
+	return false
+}
+
+// VisitCallExpression evaluates the callee and its arguments then calls it
+func (i *Interpreter) VisitCallExpression(e *CallExpr) interface{} {
+	callee := i.evaluate(e.Callee)
+	arguments := make([]interface{}, 0, len(e.Arguments))
+	for _, argument := range e.Arguments {
+		arguments = append(arguments, i.evaluate(argument))
+	}
+
+	function, ok := callee.(LoxCallable)
+	if !ok {
+		i.runTimeError(e.Paren, "Can only call functions and classes.")
 	}
-	return reflect.DeepEqual(left, right)
+	if len(arguments) != function.Arity() {
+		i.runTimeError(e.Paren, fmt.Sprintf("Expected %d arguments but got %d.", function.Arity(), len(arguments)))
+	}
+	if len(i.frames) >= maxCallDepth {
+		i.runTimeError(e.Paren, "Stack overflow.")
+	}
+	i.frames = append(i.frames, parseerror.Frame{Function: frameName(function), Call: e.Paren})
+	value := function.Call(i, arguments)
+	i.frames = i.frames[:len(i.frames)-1]
+	return value
+}
+
+// frameName names a callable in tracebacks
+func frameName(function LoxCallable) string {
+	switch f := function.(type) {
+	case *LoxFunction:
+		return f.Declaration.Name.Lexeme
+	case *LoxClass:
+		return f.Name
+	}
+	return fmt.Sprint(function)
 }
 
-// VisitCallExpression ...
-func (i Interpreter) VisitCallExpression(e *CallExpr) interface{} {
-	return ""
+// VisitConditionalExpression evaluates only the branch picked by the
+// truthiness of the condition
+func (i *Interpreter) VisitConditionalExpression(e *ConditionalExpr) interface{} {
+	if i.isTruthy(i.evaluate(e.Condition)) {
+		return i.evaluate(e.ThenBranch)
+	}
+	return i.evaluate(e.ElseBranch)
 }
 
-// VisitGetExpression ...
-func (i Interpreter) VisitGetExpression(e *GetExpr) interface{} {
-	return ""
+// VisitGetExpression reads a property off an instance
+func (i *Interpreter) VisitGetExpression(e *GetExpr) interface{} {
+	object := i.evaluate(e.Expression)
+	instance, ok := object.(*LoxInstance)
+	if !ok {
+		i.runTimeError(e.Name, "Only instances have properties.")
+	}
+	value, err := instance.Get(e.Name)
+	if err != nil {
+		panic(err)
+	}
+	return value
 }
 
 // VisitGroupExpression resturns the result of values in parenthesis
 // expression
-func (i Interpreter) VisitGroupExpression(e *GroupExpr) interface{} {
+func (i *Interpreter) VisitGroupExpression(e *GroupExpr) interface{} {
 	return i.evaluate(e.Expression)
 }
 
 // evaluate is a helper that revisits the interpretor
-func (i Interpreter) evaluate(e Expr) interface{} {
+func (i *Interpreter) evaluate(e Expr) interface{} {
 	return e.Accept(i)
 }
 
 // VisitLiteralExpression returns the runtime value the parser took
-func (i Interpreter) VisitLiteralExpression(e *LiteralExpr) interface{} {
+func (i *Interpreter) VisitLiteralExpression(e *LiteralExpr) interface{} {
 	return e.Object
 }
 
-// VisitLogicalExpression ...
-func (i Interpreter) VisitLogicalExpression(e *LogicalExpr) interface{} {
-	return ""
+// VisitLogicalExpression short-circuits and returns the value of the
+// operand that decided the result
+func (i *Interpreter) VisitLogicalExpression(e *LogicalExpr) interface{} {
+	left := i.evaluate(e.Left)
+	if e.Operator.Type == token.OR {
+		if i.isTruthy(left) {
+			return left
+		}
+	} else if !i.isTruthy(left) {
+		return left
+	}
+	return i.evaluate(e.Right)
+}
+
+// VisitSetExpression writes a field on an instance
+func (i *Interpreter) VisitSetExpression(e *SetExpr) interface{} {
+	object := i.evaluate(e.Object)
+	instance, ok := object.(*LoxInstance)
+	if !ok {
+		i.runTimeError(e.Name, "Only instances have fields.")
+	}
+	value := i.evaluate(e.Value)
+	instance.Set(e.Name, value)
+	return value
 }
 
-// VisitSetExpression ...
-func (i Interpreter) VisitSetExpression(e *SetExpr) interface{} {
-	return ""
+// VisitSuperExpression looks up the method on the superclass and binds it
+// to the current instance
+func (i *Interpreter) VisitSuperExpression(e *SuperExpr) interface{} {
+	distance := i.locals[e]
+	superclass := i.Environment.GetAt(distance, "super").(*LoxClass)
+	// this is always bound in the environment just inside the one holding super
+	object := i.Environment.GetAt(distance-1, "this").(*LoxInstance)
+	method := superclass.FindMethod(e.Method.Lexeme)
+	if method == nil {
+		i.runTimeError(e.Method, fmt.Sprintf("Undefined property '%s'.", e.Method.Lexeme))
+	}
+	return method.Bind(object)
 }
 
-// VisitThisExpression ...
-func (i Interpreter) VisitThisExpression(e *ThisExpr) interface{} {
-	return ""
+// VisitThisExpression looks up the instance bound to the method
+func (i *Interpreter) VisitThisExpression(e *ThisExpr) interface{} {
+	return i.lookUpVariable(e.Keyword, e)
 }
 
 // VisitUnaryExpression ...
-func (i Interpreter) VisitUnaryExpression(e *UnaryExpr) interface{} {
+func (i *Interpreter) VisitUnaryExpression(e *UnaryExpr) interface{} {
 	right := i.evaluate(e.Right)
 	switch e.Operator.Type {
 	case token.MINUS:
-		value := -right.(float64)
-		return value
+		i.checkOneNumberOperand(e.Operator, right)
+		return -right.(float64)
 	case token.BANG:
-		return !i.isTruthy(true)
+		return !i.isTruthy(right)
 	}
 	return nil
 }
 
 // isTruthy returns false and nil object as falsey and everything else as
 // truthy
-func (i Interpreter) isTruthy(obj interface{}) bool {
+func (i *Interpreter) isTruthy(obj interface{}) bool {
 	if obj == nil {
 		return false
 	}
@@ -189,25 +358,140 @@ func (i Interpreter) isTruthy(obj interface{}) bool {
 }
 
 // VisitVariableExpression ...
-func (i Interpreter) VisitVariableExpression(e *VariableExpr) interface{} {
-	return i.Environment.Get(e.Name)
+func (i *Interpreter) VisitVariableExpression(e *VariableExpr) interface{} {
+	return i.lookUpVariable(e.Name, e)
+}
+
+// VisitBlockStmt runs the block statements in a new nested environment
+func (i *Interpreter) VisitBlockStmt(e *BlockStmt) interface{} {
+	i.executeBlock(e.Statements, environment.NewEnvironment(i.Environment))
+	return nil
+}
+
+// VisitClassStmt binds the class and its methods to the class name
+func (i *Interpreter) VisitClassStmt(e *ClassStmt) interface{} {
+	var superclass *LoxClass
+	if e.Superclass != nil {
+		class, ok := i.evaluate(e.Superclass).(*LoxClass)
+		if !ok {
+			i.runTimeError(e.Superclass.Name, "Superclass must be a class.")
+		}
+		superclass = class
+	}
+
+	i.Environment.Define(e.Name.Lexeme, nil)
+	if superclass != nil {
+		i.Environment = environment.NewEnvironment(i.Environment)
+		i.Environment.Define("super", superclass)
+	}
+
+	methods := make(map[string]*LoxFunction)
+	for _, method := range e.Methods {
+		methods[method.Name.Lexeme] = &LoxFunction{
+			Declaration:   method,
+			Closure:       i.Environment,
+			IsInitializer: method.Name.Lexeme == "init",
+		}
+	}
+	class := &LoxClass{Name: e.Name.Lexeme, Superclass: superclass, Methods: methods}
+	if superclass != nil {
+		i.Environment = i.Environment.Enclosing
+	}
+	i.Environment.Define(e.Name.Lexeme, class)
+	return nil
+}
+
+// VisitFunctionStmt binds the function declaration to its name, capturing
+// the current environment as the function's closure
+func (i *Interpreter) VisitFunctionStmt(e *FunctionStmt) interface{} {
+	i.Environment.Define(e.Name.Lexeme, &LoxFunction{Declaration: e, Closure: i.Environment})
+	return nil
+}
+
+// VisitReturnStmt unwinds to the enclosing function call with the value
+func (i *Interpreter) VisitReturnStmt(e *ReturnStmt) interface{} {
+	var value interface{}
+	if e.Value != nil {
+		value = i.evaluate(e.Value)
+	}
+	panic(&Return{Value: value})
+}
+
+// VisitIfStmt executes the branch picked by the truthiness of the condition
+func (i *Interpreter) VisitIfStmt(e *IfStmt) interface{} {
+	if i.isTruthy(i.evaluate(e.Condition)) {
+		i.execute(e.ThenBranch)
+	} else if e.ElseBranch != nil {
+		i.execute(e.ElseBranch)
+	}
+	return nil
+}
+
+// breakLoop unwinds the Go stack to the innermost running loop, ending it
+type breakLoop struct{}
+
+// continueLoop unwinds the Go stack to the innermost running loop, moving
+// on to its next iteration
+type continueLoop struct{}
+
+// VisitWhileStmt executes the body until the condition is falsey or a
+// break is executed
+func (i *Interpreter) VisitWhileStmt(e *WhileStmt) interface{} {
+	for i.isTruthy(i.evaluate(e.Condition)) {
+		if broke := i.executeLoopBody(e.Body); broke {
+			break
+		}
+		if e.Increment != nil {
+			i.evaluate(e.Increment)
+		}
+	}
+	return nil
+}
+
+// executeLoopBody runs one iteration of a loop body and reports whether
+// it was ended by a break
+func (i *Interpreter) executeLoopBody(body Stmt) (broke bool) {
+	defer func() {
+		if r := recover(); r != nil {
+			switch r.(type) {
+			case *breakLoop:
+				broke = true
+			case *continueLoop:
+			default:
+				panic(r)
+			}
+		}
+	}()
+	i.execute(body)
+	return false
+}
+
+// VisitBreakStmt unwinds to the enclosing loop and ends it
+func (i *Interpreter) VisitBreakStmt(e *BreakStmt) interface{} {
+	panic(&breakLoop{})
+}
+
+// VisitContinueStmt unwinds to the enclosing loop and starts the next
+// iteration
+func (i *Interpreter) VisitContinueStmt(e *ContinueStmt) interface{} {
+	panic(&continueLoop{})
 }
 
 // VisitPrintStmt ...
-func (i Interpreter) VisitPrintStmt(e *PrintStmt) interface{} {
+func (i *Interpreter) VisitPrintStmt(e *PrintStmt) interface{} {
 	value := i.evaluate(e.Expression)
 	fmt.Println(fmt.Sprint(value))
 	return nil
 }
 
 // VisitExpressionStmt ...
-func (i Interpreter) VisitExpressionStmt(e *ExpressionStmt) interface{} {
+func (i *Interpreter) VisitExpressionStmt(e *ExpressionStmt) interface{} {
 	i.evaluate(e.Expression)
 	return nil
 }
 
 // VisitVarStmt ...
-func (i Interpreter) VisitVarStmt(e *VarStmt) interface{} {
+func (i *Interpreter) VisitVarStmt(e *VarStmt) interface{} {
 	var value interface{}
 	if e.Initializer != nil {
 		value = i.evaluate(e.Initializer)
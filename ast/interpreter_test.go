@@ -0,0 +1,554 @@
+package ast_test
+
+import (
+	"fmt"
+	"lo/ast"
+	"lo/parseerror"
+	"lo/parser"
+	"lo/resolver"
+	"lo/scanner"
+	"lo/token"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// interpret runs source in a fresh Interpreter and returns it
+func interpret(t *testing.T, source string) *ast.Interpreter {
+	t.Helper()
+	i := ast.NewInterpreter(parseerror.NewDiagnostics())
+	if err := interpretIn(t, i, source); err != nil {
+		t.Fatalf("%s", err)
+	}
+	return i
+}
+
+// interpretIn runs source in the given Interpreter and returns the runtime
+// error it stopped at
+func interpretIn(t *testing.T, i *ast.Interpreter, source string) error {
+	t.Helper()
+	sc := scanner.NewScanner(source, parseerror.NewDiagnostics())
+	tokens, err := sc.ScanTokens()
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	p := parser.NewParser(tokens, parseerror.NewDiagnostics())
+	stmts, err := p.Parse()
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	if err := resolver.NewResolver(i, parseerror.NewDiagnostics()).Resolve(stmts); err != nil {
+		t.Fatalf("%s", err)
+	}
+	return i.Interpret(stmts)
+}
+
+// global looks up a global variable in the Interpreter
+func global(t *testing.T, i *ast.Interpreter, name string) interface{} {
+	t.Helper()
+	value, err := i.Environment.Get(token.Token{Type: token.IDENTIFIER, Lexeme: name})
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	return value
+}
+
+// checkGlobals compares the global variables of the Interpreter with the
+// expected values by name
+func checkGlobals(t *testing.T, i *ast.Interpreter, expected map[string]interface{}) {
+	t.Helper()
+	for name, value := range expected {
+		if got := global(t, i, name); got != value {
+			t.Errorf("expected %s to be %v but got %v", name, value, got)
+		}
+	}
+}
+
+func TestBlockScope(t *testing.T) {
+	i := interpret(t, `
+	var a = "global a";
+	var b = "global b";
+	var inner;
+	{
+		var a = "inner a";
+		b = "assigned b";
+		inner = a;
+	}
+	`)
+
+	checkGlobals(t, i, map[string]interface{}{
+		"a":     "global a",
+		"b":     "assigned b",
+		"inner": "inner a",
+	})
+	if i.Environment.Enclosing != nil {
+		t.Errorf("expected the global environment to be restored after the block")
+	}
+}
+
+func TestUnicode(t *testing.T) {
+	i := interpret(t, `
+	var größe = "naïve ";
+	fun grüß(wer) { return größe + wer + " ☕\u{1F600}"; }
+	var π = grüß("café");
+	`)
+	if got := global(t, i, "π"); got != "naïve café ☕😀" {
+		t.Errorf("expected the UTF-8 to be kept intact but got %q", got)
+	}
+}
+
+func TestControlFlow(t *testing.T) {
+	i := interpret(t, `
+	var sum = 0;
+	for (var n = 1; n <= 4; n = n + 1) {
+		sum = sum + n;
+	}
+	var countdown = 3;
+	while (countdown > 0) countdown = countdown - 1;
+	var branch;
+	if (countdown) branch = "then"; else branch = "else";
+	var nilBranch = "untouched";
+	if (nil) nilBranch = "then";
+	`)
+
+	checkGlobals(t, i, map[string]interface{}{
+		"sum":       10.0,
+		"countdown": 0.0,
+		"branch":    "then",
+		"nilBranch": "untouched",
+	})
+}
+
+func TestLogicalShortCircuit(t *testing.T) {
+	i := interpret(t, `
+	var andCalls = 0;
+	var orCalls = 0;
+	var andValue = nil and (andCalls = andCalls + 1);
+	var orValue = "yes" or (orCalls = orCalls + 1);
+	var andRight = true and "right";
+	var orRight = false or 0;
+	`)
+
+	checkGlobals(t, i, map[string]interface{}{
+		"andCalls": 0.0,
+		"orCalls":  0.0,
+		"andValue": nil,
+		"orValue":  "yes",
+		"andRight": "right",
+		"orRight":  0.0,
+	})
+}
+
+func TestFunctions(t *testing.T) {
+	i := interpret(t, `
+	fun fib(n) {
+		if (n < 2) return n;
+		return fib(n - 1) + fib(n - 2);
+	}
+	fun firstOver(limit) {
+		for (var n = 0; ; n = n + 1) {
+			{
+				if (n > limit) return n;
+			}
+		}
+	}
+	fun nothing() {}
+	var fibValue = fib(10);
+	var over = firstOver(3);
+	var empty = nothing();
+	`)
+
+	checkGlobals(t, i, map[string]interface{}{
+		"fibValue": 55.0,
+		"over":     4.0,
+		"empty":    nil,
+	})
+	if i.Environment != i.Globals {
+		t.Errorf("expected return to restore the global environment")
+	}
+}
+
+func TestFunctionEquality(t *testing.T) {
+	i := interpret(t, `
+	fun mk() { fun f() {} return f; }
+	var made = mk();
+	var distinct = mk() == mk();
+	var same = made == made;
+	`)
+	if global(t, i, "distinct") != false {
+		t.Errorf("expected two functions from separate calls to be unequal")
+	}
+	if global(t, i, "same") != true {
+		t.Errorf("expected a function to equal itself")
+	}
+}
+
+func TestClosures(t *testing.T) {
+	i := interpret(t, `
+	fun makeCounter() {
+		var i = 0;
+		fun count() {
+			i = i + 1;
+			return i;
+		}
+		return count;
+	}
+	var counter = makeCounter();
+	var first = counter();
+	var second = counter();
+	var other = makeCounter()();
+
+	fun makeAdder(n) {
+		fun add(x) { return x + n; }
+		return add;
+	}
+	var addTwo = makeAdder(2);
+	var added = addTwo(40);
+
+	fun apply(callback, value) { return callback(value); }
+	var applied = apply(makeAdder(1), 1);
+	`)
+
+	checkGlobals(t, i, map[string]interface{}{
+		"first":   1.0,
+		"second":  2.0,
+		"other":   1.0,
+		"added":   42.0,
+		"applied": 2.0,
+	})
+	if _, found := i.Globals.Values["i"]; found {
+		t.Errorf("expected the counter variable to live in the closure, not the globals")
+	}
+}
+
+func TestInstanceEquality(t *testing.T) {
+	i := interpret(t, `
+	class A {}
+	class Point { init(x) { this.x = x; } }
+	var a = A();
+	var empty = A() == A();
+	var sameFields = Point(1) == Point(1);
+	var same = a == a;
+	var sameClass = A == A;
+	var differ = a != A();
+	`)
+
+	checkGlobals(t, i, map[string]interface{}{
+		"empty":      false,
+		"sameFields": false,
+		"same":       true,
+		"sameClass":  true,
+		"differ":     true,
+	})
+}
+
+func TestClasses(t *testing.T) {
+	i := interpret(t, `
+	class Counter {
+		init(start) {
+			this.count = start;
+			return;
+		}
+		increment() {
+			this.count = this.count + 1;
+			return this;
+		}
+	}
+	var counter = Counter(1);
+	counter.increment().increment();
+	var count = counter.count;
+	var method = counter.increment;
+	method();
+	var bound = counter.count;
+	var reinit = counter.init(10) == counter;
+	counter.label = "fields";
+	var label = counter.label;
+	`)
+
+	checkGlobals(t, i, map[string]interface{}{
+		"count":  3.0,
+		"bound":  4.0,
+		"reinit": true,
+		"label":  "fields",
+	})
+	if got := fmt.Sprint(global(t, i, "counter")); got != "Counter instance" {
+		t.Errorf("expected Counter instance but got %s", got)
+	}
+}
+
+func TestInheritance(t *testing.T) {
+	i := interpret(t, `
+	class Animal {
+		init(name) { this.name = name; }
+		speak() { return this.name + " makes a sound"; }
+		kind() { return "animal"; }
+	}
+	class Dog < Animal {
+		speak() { return super.speak() + " and barks"; }
+	}
+	class Puppy < Dog {
+		speak() { return super.speak() + " softly"; }
+	}
+	var dog = Dog("Rex");
+	var speech = dog.speak();
+	var inherited = dog.kind();
+	var puppy = Puppy("Bit").speak();
+	`)
+
+	checkGlobals(t, i, map[string]interface{}{
+		"speech":    "Rex makes a sound and barks",
+		"inherited": "animal",
+		"puppy":     "Bit makes a sound and barks softly",
+	})
+}
+
+func TestBreakContinue(t *testing.T) {
+	i := interpret(t, `
+	var evens = 0;
+	var iterations = 0;
+	for (var n = 0; n < 10; n = n + 1) {
+		iterations = iterations + 1;
+		if (n == 7) break;
+		if (n == 1 or n == 3 or n == 5) continue;
+		evens = evens + 1;
+	}
+	var outer = 0;
+	while (outer < 3) {
+		outer = outer + 1;
+		while (true) break;
+	}
+	fun find() {
+		for (var n = 0; ; n = n + 1) {
+			if (n < 5) continue;
+			return n;
+		}
+	}
+	var found = find();
+	`)
+
+	checkGlobals(t, i, map[string]interface{}{
+		"evens":      4.0,
+		"iterations": 8.0,
+		"outer":      3.0,
+		"found":      5.0,
+	})
+}
+
+func TestConditional(t *testing.T) {
+	i := interpret(t, `
+	var calls = 0;
+	fun touch(value) {
+		calls = calls + 1;
+		return value;
+	}
+	var picked = true ? touch("then") : touch("else");
+	var nested = false ? 1 : nil ? 2 : 3;
+	`)
+
+	checkGlobals(t, i, map[string]interface{}{
+		"picked": "then",
+		"calls":  1.0,
+		"nested": 3.0,
+	})
+}
+
+func TestPower(t *testing.T) {
+	i := interpret(t, `
+	var negated = -2 ** 2;
+	var chained = 2 ** 3 ** 2;
+	var fraction = 4 ** -0.5;
+	`)
+
+	checkGlobals(t, i, map[string]interface{}{
+		"negated":  -4.0,
+		"chained":  512.0,
+		"fraction": 0.5,
+	})
+}
+
+func TestRunTimeErrors(t *testing.T) {
+	testCases := []struct {
+		source  string
+		lexeme  string
+		message string
+	}{
+		{`-"a";`, "-", "Operand a must be a number"},
+		{`1 - nil;`, "-", "Operand 1 and <nil> must be a number"},
+		{`1 < "2";`, "<", "Operand 1 and 2 must be a number"},
+		{`nil + 1;`, "+", "Operand <nil> and 1 must be numbers or strings"},
+		{`print missing;`, "missing", "Undefined variable 'missing'."},
+		{`missing = 1;`, "missing", "Undefined variable 'missing'."},
+		{`"str"();`, ")", "Can only call functions and classes."},
+		{`fun pair(a, b) {} pair(1);`, ")", "Expected 2 arguments but got 1."},
+		{`class Pair { init(a, b) {} } Pair(1);`, ")", "Expected 2 arguments but got 1."},
+		{`class Empty {} Empty().nothing;`, "nothing", "Undefined property 'nothing'."},
+		{`"str".length;`, "length", "Only instances have properties."},
+		{`"str".length = 1;`, "length", "Only instances have fields."},
+		{`var NotClass = 1; class A < NotClass {}`, "NotClass", "Superclass must be a class."},
+		{`class A {} class B < A { m() { super.m(); } } B().m();`, "m", "Undefined property 'm'."},
+	}
+	for _, tt := range testCases {
+		err := interpretIn(t, ast.NewInterpreter(parseerror.NewDiagnostics()), tt.source)
+		runTimeError, ok := err.(*parseerror.RunTimeError)
+		if !ok {
+			t.Errorf("%s: expected a RunTimeError but got %v", tt.source, err)
+			continue
+		}
+		if runTimeError.Token.Lexeme != tt.lexeme || runTimeError.Message != tt.message {
+			t.Errorf("%s: expected %s at %q but got %s at %q",
+				tt.source, tt.message, tt.lexeme, runTimeError.Message, runTimeError.Token.Lexeme)
+		}
+	}
+}
+
+func TestRunTimeErrorStopsExecution(t *testing.T) {
+	i := ast.NewInterpreter(parseerror.NewDiagnostics())
+	err := interpretIn(t, i, `
+	var reached = false;
+	fun fail() {
+		{
+			var local = 1;
+			return local + nil;
+		}
+	}
+	fail();
+	reached = true;
+	`)
+	if err == nil {
+		t.Fatalf("expected a runtime error")
+	}
+	if global(t, i, "reached") != false {
+		t.Errorf("expected execution to stop at the runtime error")
+	}
+	if i.Environment != i.Globals {
+		t.Errorf("expected the global environment to be restored after the error")
+	}
+
+	if err := interpretIn(t, i, `reached = !reached;`); err != nil {
+		t.Fatalf("expected the interpreter to keep working but got %s", err)
+	}
+	if global(t, i, "reached") != true {
+		t.Errorf("expected the interpreter to keep working after a runtime error")
+	}
+}
+
+func TestRunTimeErrorStack(t *testing.T) {
+	i := ast.NewInterpreter(parseerror.NewDiagnostics())
+	err := interpretIn(t, i, `
+	class Point {
+		init(x) {
+			this.x = x + nil;
+		}
+	}
+	fun make(x) {
+		return Point(x);
+	}
+	make(1);
+	`)
+	runTimeError, ok := err.(*parseerror.RunTimeError)
+	if !ok {
+		t.Fatalf("expected a RunTimeError but got %v", err)
+	}
+
+	expected := []struct {
+		function string
+		line     int
+	}{
+		{"Point", 8}, {"make", 10},
+	}
+	if len(runTimeError.Stack) != len(expected) {
+		t.Fatalf("expected %d frames but got %+v", len(expected), runTimeError.Stack)
+	}
+	for idx, frame := range runTimeError.Stack {
+		if frame.Function != expected[idx].function || frame.Call.Line != expected[idx].line {
+			t.Errorf("[frame %d] expected %s called on line %d but got %s called on line %d",
+				idx, expected[idx].function, expected[idx].line, frame.Function, frame.Call.Line)
+		}
+	}
+
+	err = interpretIn(t, i, `nil + 1;`)
+	if runTimeError, ok := err.(*parseerror.RunTimeError); !ok || runTimeError.Stack != nil {
+		t.Errorf("expected a top level error to have no stack but got %v", err)
+	}
+}
+
+func TestStackOverflow(t *testing.T) {
+	i := ast.NewInterpreter(parseerror.NewDiagnostics())
+	err := interpretIn(t, i, `fun f() { f(); } f();`)
+	runTimeError, ok := err.(*parseerror.RunTimeError)
+	if !ok || runTimeError.Message != "Stack overflow." {
+		t.Fatalf("expected a stack overflow error but got %v", err)
+	}
+	if len(runTimeError.Stack) != 1000 || runTimeError.Stack[0].Function != "f" {
+		t.Errorf("expected a traceback of the recursive calls but got %d frames", len(runTimeError.Stack))
+	}
+	rendered := parseerror.NewRenderer("so.lo", `fun f() { f(); } f();`, false).Render(runTimeError)
+	expected := "  = traceback:\n" +
+		"      at f (so.lo:1) [repeated 999 more times]\n" +
+		"      at <script> (so.lo:1)\n"
+	if !strings.HasSuffix(rendered, expected) {
+		t.Errorf("expected the recursive calls to be folded into\n%s\nbut got\n%s", expected, rendered)
+	}
+
+	if err := interpretIn(t, i, `fun down(n) { if (n > 0) down(n - 1); } down(900);`); err != nil {
+		t.Errorf("expected recursion under the limit to work but got %s", err)
+	}
+}
+
+func TestNilDiagnostics(t *testing.T) {
+	tokens, err := scanner.NewScanner("print 1 @;", nil).ScanTokens()
+	if err == nil {
+		t.Fatalf("expected the scanner error to be returned")
+	}
+	if _, err := parser.NewParser(tokens, nil).Parse(); err != nil {
+		t.Fatalf("%s", err)
+	}
+	tokens, _ = scanner.NewScanner("return 1;", nil).ScanTokens()
+	stmts, _ := parser.NewParser(tokens, nil).Parse()
+
+	i := ast.NewInterpreter(nil)
+	if err := resolver.NewResolver(i, nil).Resolve(stmts); err == nil {
+		t.Errorf("expected the resolver error to be returned without diagnostics")
+	}
+	if err := interpretIn(t, i, `var a = nil + 1;`); err == nil {
+		t.Errorf("expected the runtime error to be returned without diagnostics")
+	}
+}
+
+func TestConcurrentInterpreters(t *testing.T) {
+	sources := []string{
+		`var total = 0; for (var n = 0; n < 1000; n = n + 1) total = total + n;`,
+		`var total = 0; for (var n = 0; n < 1000; n = n + 1) total = total + n; total = total + nil;`,
+	}
+	diagnostics := make([]*parseerror.Diagnostics, len(sources))
+	var wg sync.WaitGroup
+	for idx, source := range sources {
+		diagnostics[idx] = parseerror.NewDiagnostics()
+		wg.Add(1)
+		go func(source string, diagnostics *parseerror.Diagnostics) {
+			defer wg.Done()
+			sc := scanner.NewScanner(source, diagnostics)
+			tokens, err := sc.ScanTokens()
+			if err != nil {
+				return
+			}
+			stmts, err := parser.NewParser(tokens, diagnostics).Parse()
+			if err != nil {
+				return
+			}
+			i := ast.NewInterpreter(diagnostics)
+			if err := resolver.NewResolver(i, diagnostics).Resolve(stmts); err != nil {
+				return
+			}
+			i.Interpret(stmts)
+		}(source, diagnostics[idx])
+	}
+	wg.Wait()
+
+	if diagnostics[0].HadError() || diagnostics[0].HadRunTimeError() {
+		t.Errorf("expected the first script to run cleanly but got %v", diagnostics[0].Errors())
+	}
+	if !diagnostics[1].HadRunTimeError() || len(diagnostics[1].Errors()) != 1 {
+		t.Errorf("expected the second script to report one runtime error but got %v", diagnostics[1].Errors())
+	}
+}
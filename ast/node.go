@@ -0,0 +1,14 @@
+package ast
+
+import "lo/token"
+
+// Node is embedded in every expression and statement to record the range
+// of source it was parsed from
+type Node struct {
+	Range token.Span
+}
+
+// Span returns the range of source the node was parsed from
+func (n *Node) Span() token.Span {
+	return n.Range
+}
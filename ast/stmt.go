@@ -1,40 +1,282 @@
 package ast
 
-import "lo/token"
+import (
+	"fmt"
+	"lo/token"
+	"strings"
+)
 
 // Stmt interface for statements
 type Stmt interface {
-	Accept(i Interpreter) interface{}
+	Accept(v StmtVisitor) interface{}
+	Span() token.Span
+}
+
+// StmtVisitor is implemented by the passes that walk statements i.e. the
+// Interpreter and the resolver
+type StmtVisitor interface {
+	VisitBlockStmt(stmt *BlockStmt) interface{}
+	VisitBreakStmt(stmt *BreakStmt) interface{}
+	VisitClassStmt(stmt *ClassStmt) interface{}
+	VisitContinueStmt(stmt *ContinueStmt) interface{}
+	VisitExpressionStmt(stmt *ExpressionStmt) interface{}
+	VisitFunctionStmt(stmt *FunctionStmt) interface{}
+	VisitIfStmt(stmt *IfStmt) interface{}
+	VisitPrintStmt(stmt *PrintStmt) interface{}
+	VisitReturnStmt(stmt *ReturnStmt) interface{}
+	VisitVarStmt(stmt *VarStmt) interface{}
+	VisitWhileStmt(stmt *WhileStmt) interface{}
+}
+
+// BlockStmt is a list of statements enclosed in braces
+type BlockStmt struct {
+	Node
+	Statements []Stmt
+}
+
+// Accept visits the BlockStmt
+func (stmt *BlockStmt) Accept(v StmtVisitor) interface{} {
+	return v.VisitBlockStmt(stmt)
+}
+
+func (stmt *BlockStmt) String() string {
+	var sb strings.Builder
+	sb.WriteString("(block")
+	for _, s := range stmt.Statements {
+		sb.WriteString(" ")
+		sb.WriteString(fmt.Sprint(s))
+	}
+	sb.WriteString(")")
+	return sb.String()
 }
 
 // PrintStmt ...
 type PrintStmt struct {
+	Node
 	Expression Expr
 }
 
 // Accept visits the PrintStmt
-func (stmt *PrintStmt) Accept(i Interpreter) interface{} {
-	return i.VisitPrintStmt(stmt)
+func (stmt *PrintStmt) Accept(v StmtVisitor) interface{} {
+	return v.VisitPrintStmt(stmt)
+}
+
+func (stmt *PrintStmt) String() string {
+	var sb strings.Builder
+	sb.WriteString("(print ")
+	sb.WriteString(fmt.Sprint(stmt.Expression))
+	sb.WriteString(")")
+	return sb.String()
 }
 
 // ExpressionStmt ...
 type ExpressionStmt struct {
+	Node
 	Expression Expr
 }
 
 // Accept visits the ExpressionStmt
-func (stmt *ExpressionStmt) Accept(i Interpreter) interface{} {
-	return i.VisitExpressionStmt(stmt)
+func (stmt *ExpressionStmt) Accept(v StmtVisitor) interface{} {
+	return v.VisitExpressionStmt(stmt)
+}
+
+func (stmt *ExpressionStmt) String() string {
+	return fmt.Sprint(stmt.Expression)
 }
 
 // VarStmt statement
 type VarStmt struct {
+	Node
 	Name        token.Token
 	Initializer Expr
 }
 
 // Accept visits the VarStmt
-func (stmt *VarStmt) Accept(i Interpreter) interface{} {
-	return i.VisitVarStmt(stmt)
+func (stmt *VarStmt) Accept(v StmtVisitor) interface{} {
+	return v.VisitVarStmt(stmt)
+
+}
+
+func (stmt *VarStmt) String() string {
+	var sb strings.Builder
+	sb.WriteString("(var ")
+	sb.WriteString(stmt.Name.Lexeme)
+	if stmt.Initializer != nil {
+		sb.WriteString(" ")
+		sb.WriteString(fmt.Sprint(stmt.Initializer))
+	}
+	sb.WriteString(")")
+	return sb.String()
+}
+
+// IfStmt executes ThenBranch when Condition is truthy and the optional
+// ElseBranch otherwise
+type IfStmt struct {
+	Node
+	Condition  Expr
+	ThenBranch Stmt
+	ElseBranch Stmt
+}
+
+// Accept visits the IfStmt
+func (stmt *IfStmt) Accept(v StmtVisitor) interface{} {
+	return v.VisitIfStmt(stmt)
+}
+
+func (stmt *IfStmt) String() string {
+	var sb strings.Builder
+	sb.WriteString("(if ")
+	sb.WriteString(fmt.Sprint(stmt.Condition))
+	sb.WriteString(" ")
+	sb.WriteString(fmt.Sprint(stmt.ThenBranch))
+	if stmt.ElseBranch != nil {
+		sb.WriteString(" ")
+		sb.WriteString(fmt.Sprint(stmt.ElseBranch))
+	}
+	sb.WriteString(")")
+	return sb.String()
+}
+
+// WhileStmt executes Body for as long as Condition is truthy. Increment is
+// set on loops desugared from a for loop and is evaluated after every run
+// of the Body, including ones cut short by a continue
+type WhileStmt struct {
+	Node
+	Condition Expr
+	Body      Stmt
+	Increment Expr
+}
+
+// Accept visits the WhileStmt
+func (stmt *WhileStmt) Accept(v StmtVisitor) interface{} {
+	return v.VisitWhileStmt(stmt)
+}
+
+func (stmt *WhileStmt) String() string {
+	var sb strings.Builder
+	sb.WriteString("(while ")
+	sb.WriteString(fmt.Sprint(stmt.Condition))
+	sb.WriteString(" ")
+	sb.WriteString(fmt.Sprint(stmt.Body))
+	if stmt.Increment != nil {
+		sb.WriteString(" ")
+		sb.WriteString(fmt.Sprint(stmt.Increment))
+	}
+	sb.WriteString(")")
+	return sb.String()
+}
+
+// FunctionStmt declares a named function with its parameters and body
+type FunctionStmt struct {
+	Node
+	Name   token.Token
+	Params []token.Token
+	Body   []Stmt
+}
+
+// Accept visits the FunctionStmt
+func (stmt *FunctionStmt) Accept(v StmtVisitor) interface{} {
+	return v.VisitFunctionStmt(stmt)
+}
+
+func (stmt *FunctionStmt) String() string {
+	var sb strings.Builder
+	sb.WriteString("(fun ")
+	sb.WriteString(stmt.Name.Lexeme)
+	sb.WriteString(" (")
+	for idx, param := range stmt.Params {
+		if idx > 0 {
+			sb.WriteString(" ")
+		}
+		sb.WriteString(param.Lexeme)
+	}
+	sb.WriteString(")")
+	for _, s := range stmt.Body {
+		sb.WriteString(" ")
+		sb.WriteString(fmt.Sprint(s))
+	}
+	sb.WriteString(")")
+	return sb.String()
+}
+
+// ReturnStmt exits the current function with an optional Value
+type ReturnStmt struct {
+	Node
+	Keyword token.Token
+	Value   Expr
+}
+
+// Accept visits the ReturnStmt
+func (stmt *ReturnStmt) Accept(v StmtVisitor) interface{} {
+	return v.VisitReturnStmt(stmt)
+}
+
+func (stmt *ReturnStmt) String() string {
+	if stmt.Value == nil {
+		return "(return)"
+	}
+	var sb strings.Builder
+	sb.WriteString("(return ")
+	sb.WriteString(fmt.Sprint(stmt.Value))
+	sb.WriteString(")")
+	return sb.String()
+}
+
+// ClassStmt declares a class, its optional Superclass and its methods
+type ClassStmt struct {
+	Node
+	Name       token.Token
+	Superclass *VariableExpr
+	Methods    []*FunctionStmt
+}
+
+// Accept visits the ClassStmt
+func (stmt *ClassStmt) Accept(v StmtVisitor) interface{} {
+	return v.VisitClassStmt(stmt)
+}
+
+func (stmt *ClassStmt) String() string {
+	var sb strings.Builder
+	sb.WriteString("(class ")
+	sb.WriteString(stmt.Name.Lexeme)
+	if stmt.Superclass != nil {
+		sb.WriteString(" < ")
+		sb.WriteString(fmt.Sprint(stmt.Superclass))
+	}
+	for _, method := range stmt.Methods {
+		sb.WriteString(" ")
+		sb.WriteString(fmt.Sprint(method))
+	}
+	sb.WriteString(")")
+	return sb.String()
+}
+
+// BreakStmt exits the innermost enclosing loop
+type BreakStmt struct {
+	Node
+	Keyword token.Token
+}
+
+// Accept visits the BreakStmt
+func (stmt *BreakStmt) Accept(v StmtVisitor) interface{} {
+	return v.VisitBreakStmt(stmt)
+}
+
+func (stmt *BreakStmt) String() string {
+	return "(break)"
+}
+
+// ContinueStmt skips to the next iteration of the innermost enclosing loop
+type ContinueStmt struct {
+	Node
+	Keyword token.Token
+}
+
+// Accept visits the ContinueStmt
+func (stmt *ContinueStmt) Accept(v StmtVisitor) interface{} {
+	return v.VisitContinueStmt(stmt)
+}
 
+func (stmt *ContinueStmt) String() string {
+	return "(continue)"
 }
@@ -7,15 +7,17 @@ import (
 )
 
 // Environment has a map of variable names to their accompanying
-// values
+// values and a link to the Environment enclosing it
 type Environment struct {
-	Values map[string]interface{}
+	Values    map[string]interface{}
+	Enclosing *Environment
 }
 
-// NewEnvironment creates a new instance for Environment
-func NewEnvironment() Environment {
+// NewEnvironment creates a new instance for Environment nested inside
+// enclosing. The global Environment has a nil enclosing
+func NewEnvironment(enclosing *Environment) *Environment {
 	values := make(map[string]interface{})
-	return Environment{Values: values}
+	return &Environment{Values: values, Enclosing: enclosing}
 }
 
 // Define binds a variable to a value
@@ -23,21 +25,50 @@ func (e *Environment) Define(name string, value interface{}) {
 	e.Values[name] = value
 }
 
-// Get retrieves a variable value from the environment
-func (e *Environment) Get(t token.Token) interface{} {
+// Get retrieves a variable value from the environment, walking up the
+// enclosing environments if it's not found in this one
+func (e *Environment) Get(t token.Token) (interface{}, error) {
 	value, found := e.Values[t.Lexeme]
 	if found {
-		return value
+		return value, nil
+	}
+	if e.Enclosing != nil {
+		return e.Enclosing.Get(t)
+	}
+	return nil, &parseerror.RunTimeError{Token: t, Message: fmt.Sprintf("Undefined variable '%s'.", t.Lexeme)}
+}
+
+// Assign does not create a new variable. It updates the closest
+// environment that already has the variable
+func (e *Environment) Assign(t token.Token, value interface{}) error {
+	_, found := e.Values[t.Lexeme]
+	if found {
+		e.Values[t.Lexeme] = value
+		return nil
+	}
+	if e.Enclosing != nil {
+		return e.Enclosing.Assign(t, value)
 	}
 	return &parseerror.RunTimeError{Token: t, Message: fmt.Sprintf("Undefined variable '%s'.", t.Lexeme)}
 }
 
-// Assign does not create a new variable
-func (e *Environment) Assign(t token.Token, value interface{}) interface{} {
-	v, found := e.Values[t.Lexeme]
-	if !found {
-		return &parseerror.RunTimeError{Token: t, Message: fmt.Sprintf("Undefined variable '%s'.", t.Lexeme)}
+// GetAt retrieves a variable value from the environment distance hops up
+// the enclosing chain, as worked out by the resolver
+func (e *Environment) GetAt(distance int, name string) interface{} {
+	return e.ancestor(distance).Values[name]
+}
+
+// AssignAt updates a variable in the environment distance hops up the
+// enclosing chain, as worked out by the resolver
+func (e *Environment) AssignAt(distance int, t token.Token, value interface{}) {
+	e.ancestor(distance).Values[t.Lexeme] = value
+}
+
+// ancestor walks distance hops up the enclosing chain
+func (e *Environment) ancestor(distance int) *Environment {
+	env := e
+	for hop := 0; hop < distance; hop++ {
+		env = env.Enclosing
 	}
-	e.Values[t.Lexeme] = v
-	return nil
+	return env
 }
@@ -4,53 +4,69 @@ import (
 	"bufio"
 	"flag"
 	"fmt"
+	"io"
 	"io/ioutil"
 	"lo/ast"
+	"lo/parseerror"
 	"lo/parser"
+	"lo/resolver"
 	"lo/scanner"
 	"os"
 )
 
 // Lox language
 type Lox struct {
-	HadRunTimeError bool
-	HadError        bool
-	Interpreter     *ast.Interpreter
+	Diagnostics *parseerror.Diagnostics
+	Interpreter *ast.Interpreter
+	// JSONDiagnostics prints the errors as a JSON array instead of
+	// rendering them for people
+	JSONDiagnostics bool
 }
 
 // NewLox instance
 func NewLox() *Lox {
-	return &Lox{HadRunTimeError: false, HadError: false, Interpreter: ast.NewInterpreter()}
+	diagnostics := parseerror.NewDiagnostics()
+	return &Lox{Diagnostics: diagnostics, Interpreter: ast.NewInterpreter(diagnostics)}
 }
 
 // Read a lox filePath and load the content to the run() function
 func (l *Lox) runFile(fileName string) {
 	fileData, err := ioutil.ReadFile(fileName)
 	if err != nil {
-		fmt.Println(err)
-		l.HadError = true
-	}
-	l.run(string(fileData))
-	if l.HadError {
+		if l.JSONDiagnostics {
+			// tools expect every failure as JSON, placed nowhere in the file
+			l.Diagnostics.Report(&parseerror.IOError{Err: err})
+			l.printErrors(fileName, "")
+		} else {
+			fmt.Println(err)
+		}
 		os.Exit(65)
 	}
+	l.run(fileName, string(fileData))
+	l.exitOnError()
+}
 
-	if l.HadRunTimeError {
-		os.Exit(70)
-	}
+// runPipe interprets the whole of a piped stdin as one script, scanning it
+// as it is read
+func (l *Lox) runPipe() {
+	l.runStream("<stdin>", os.Stdin)
+	l.exitOnError()
 }
 
-// runPrompt creates a CLI that loads lox content
-func (l *Lox) runPrompt() {
-	reader := bufio.NewReader(os.Stdin)
-	if l.HadError {
+// exitOnError exits with the status for the errors reported during a run
+func (l *Lox) exitOnError() {
+	if l.Diagnostics.HadError() {
 		os.Exit(65)
 	}
 
-	if l.HadRunTimeError {
+	if l.Diagnostics.HadRunTimeError() {
 		os.Exit(70)
 	}
+}
 
+// runPrompt creates a CLI that loads lox content
+func (l *Lox) runPrompt() {
+	reader := bufio.NewReader(os.Stdin)
 	for {
 		fmt.Print("> ")
 		line, err := reader.ReadString('\n')
@@ -61,41 +77,92 @@ func (l *Lox) runPrompt() {
 			fmt.Println("Exiting Lox REPL...")
 			os.Exit(0)
 		}
-		l.run(line)
-
+		l.run("<stdin>", line)
+		// a mistake on one line should not stop the rest of the session
+		l.Diagnostics.Reset()
 	}
 }
 
-// run interprets lox content
-func (l *Lox) run(srcData string) {
-	scanner := scanner.NewScanner(srcData)
-	tokens := scanner.ScanTokens()
-	p := parser.NewParser(tokens)
-	stmts, err := p.Parse()
-	if err != nil {
-		l.HadError = true
-		fmt.Println(err)
+// run interprets lox content read from fileName
+func (l *Lox) run(fileName string, srcData string) {
+	defer l.printErrors(fileName, srcData)
+	l.execute(scanner.NewScanner(srcData, l.Diagnostics))
+}
+
+// runStream interprets lox content pulled from reader as it is parsed. The
+// source is not kept so errors are shown without it
+func (l *Lox) runStream(fileName string, reader io.Reader) {
+	defer l.printErrors(fileName, "")
+	l.execute(scanner.NewReaderScanner(reader, l.Diagnostics))
+}
+
+// execute parses the tokens of sc, then resolves and interprets them
+func (l *Lox) execute(sc *scanner.Scanner) {
+	sc.SkipComments = true
+	// the tokens are parsed even after scanner errors so that the syntax
+	// errors are reported in the same run
+	p := parser.NewStreamParser(sc, l.Diagnostics)
+	stmts, _ := p.Parse()
+	if l.Diagnostics.HadError() {
 		return
 	}
-	if l.HadError {
+	r := resolver.NewResolver(l.Interpreter, l.Diagnostics)
+	if err := r.Resolve(stmts); err != nil {
 		return
 	}
 	l.Interpreter.Interpret(stmts)
 }
 
+// printErrors shows the errors reported during a run on the stderr,
+// pointing at the offending source or as JSON for tools
+func (l *Lox) printErrors(fileName string, srcData string) {
+	if l.JSONDiagnostics {
+		output, err := parseerror.MarshalJSONDiagnostics(fileName, srcData, l.Diagnostics.Errors())
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return
+		}
+		fmt.Fprintln(os.Stderr, string(output))
+		return
+	}
+	renderer := parseerror.NewRenderer(fileName, srcData, isTerminal(os.Stderr))
+	for _, err := range l.Diagnostics.Errors() {
+		fmt.Fprintln(os.Stderr, renderer.Render(err))
+	}
+}
+
+// isTerminal reports whether the file is a terminal rather than a pipe or
+// a regular file, in which case colours can be used
+func isTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	if err != nil {
+		return false
+	}
+	return info.Mode()&os.ModeCharDevice != 0
+}
+
 func main() {
 	flag.String("file", "", "the file path to execute")
+	diagnostics := flag.String("diagnostics", "text", "how errors are printed: text or json")
 	flag.Parse()
 
+	if *diagnostics != "text" && *diagnostics != "json" {
+		fmt.Println("Usage: ./lo [--diagnostics=text|json] [filePath]")
+		os.Exit(64)
+	}
+
 	args := flag.Args()
 
 	if len(args) > 1 {
-		fmt.Println("Usage: ./lo [filePath]")
+		fmt.Println("Usage: ./lo [--diagnostics=text|json] [filePath]")
 		os.Exit(64) // The command was used incorrectly
 	} else {
 		l := NewLox()
+		l.JSONDiagnostics = *diagnostics == "json"
 		if len(args) == 1 {
 			l.runFile(args[0])
+		} else if !isTerminal(os.Stdin) {
+			l.runPipe()
 		} else {
 			l.runPrompt()
 		}
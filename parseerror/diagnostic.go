@@ -0,0 +1,88 @@
+package parseerror
+
+import "lo/token"
+
+// Diagnostic describes where in the source an error happened along with
+// the extra notes and help shown when it is rendered
+type Diagnostic struct {
+	Code    string
+	Message string
+	Line    int
+	Column  int
+	Span    token.Span
+	Notes   []string
+	Help    string
+	Trace   []TraceLine
+}
+
+// TraceLine is a line of a traceback: the function that was running and
+// where it was when the error happened. Repeated counts the identical
+// lines that follow it and were folded into it, as in deep recursion
+type TraceLine struct {
+	Function string
+	Line     int
+	Column   int
+	Repeated int
+}
+
+// Diagnoser is implemented by the errors that can be placed in the source
+type Diagnoser interface {
+	error
+	Diagnostic() Diagnostic
+}
+
+// tokenDiagnostic places a message on the given token
+func tokenDiagnostic(code string, t token.Token, message string) Diagnostic {
+	return Diagnostic{Code: code, Message: message, Line: t.Line, Column: t.Column, Span: token.SpanOf(t)}
+}
+
+// Diagnostic of an IOError has no position as it is about the whole source
+func (e *IOError) Diagnostic() Diagnostic {
+	return Diagnostic{Code: CodeIO, Message: e.Err.Error()}
+}
+
+// Diagnostic places the SyntaxError on its token
+func (e *SyntaxError) Diagnostic() Diagnostic {
+	return tokenDiagnostic(CodeSyntax, e.Token, e.Message)
+}
+
+// Diagnostic places the ParseError on its token
+func (e *ParseError) Diagnostic() Diagnostic {
+	return tokenDiagnostic(CodeParse, e.Token, e.Message)
+}
+
+// Diagnostic places the ResolveError on its token
+func (e *ResolveError) Diagnostic() Diagnostic {
+	return tokenDiagnostic(CodeResolve, e.Token, e.Message)
+}
+
+// Diagnostic places the RunTimeError on its token. Errors raised inside
+// calls get a traceback from the innermost function out to the script
+func (e *RunTimeError) Diagnostic() Diagnostic {
+	d := tokenDiagnostic(CodeRunTime, e.Token, e.Message)
+	if len(e.Stack) == 0 {
+		return d
+	}
+	// each function is at the call site of the one it called
+	at := e.Token
+	for _, frame := range e.Stack {
+		d.Trace = appendTraceLine(d.Trace, TraceLine{Function: frame.Function, Line: at.Line, Column: at.Column})
+		at = frame.Call
+	}
+	d.Trace = appendTraceLine(d.Trace, TraceLine{Function: "<script>", Line: at.Line, Column: at.Column})
+	return d
+}
+
+// appendTraceLine adds line to the trace, folding it into the last line
+// when they are the same
+func appendTraceLine(trace []TraceLine, line TraceLine) []TraceLine {
+	if last := len(trace) - 1; last >= 0 {
+		previous := trace[last]
+		previous.Repeated = 0
+		if previous == line {
+			trace[last].Repeated++
+			return trace
+		}
+	}
+	return append(trace, line)
+}
@@ -0,0 +1,64 @@
+package parseerror
+
+// Diagnostics collects the errors reported while running a script. Every
+// run gets its own Diagnostics which is shared by the scanner, parser,
+// resolver and interpreter, so separate runs never see each other's errors.
+// A nil Diagnostics is valid and discards the errors reported to it
+type Diagnostics struct {
+	errors          []error
+	hadError        bool
+	hadRunTimeError bool
+}
+
+// NewDiagnostics creates an empty Diagnostics
+func NewDiagnostics() *Diagnostics {
+	return &Diagnostics{errors: make([]error, 0)}
+}
+
+// Report records an error. A RunTimeError marks the run as having failed
+// during execution, anything else as having failed before it
+func (d *Diagnostics) Report(err error) {
+	if d == nil {
+		return
+	}
+	d.errors = append(d.errors, err)
+	if _, ok := err.(*RunTimeError); ok {
+		d.hadRunTimeError = true
+		return
+	}
+	d.hadError = true
+}
+
+// HadError is true when a scanning, parsing or resolving error was reported
+func (d *Diagnostics) HadError() bool {
+	if d == nil {
+		return false
+	}
+	return d.hadError
+}
+
+// HadRunTimeError is true when a runtime error was reported
+func (d *Diagnostics) HadRunTimeError() bool {
+	if d == nil {
+		return false
+	}
+	return d.hadRunTimeError
+}
+
+// Errors returns the reported errors in the order they were reported
+func (d *Diagnostics) Errors() []error {
+	if d == nil {
+		return nil
+	}
+	return d.errors
+}
+
+// Reset forgets every error reported so far
+func (d *Diagnostics) Reset() {
+	if d == nil {
+		return
+	}
+	d.errors = make([]error, 0)
+	d.hadError = false
+	d.hadRunTimeError = false
+}
@@ -0,0 +1,109 @@
+package parseerror
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"unicode/utf8"
+)
+
+// SeverityError is the severity of every diagnostic lo reports today
+const SeverityError = "error"
+
+// JSONDiagnostic is the machine-readable form of an error, as consumed by
+// editors and CI. Positions are 1-based and the end is exclusive
+type JSONDiagnostic struct {
+	File      string      `json:"file"`
+	Line      int         `json:"line"`
+	Column    int         `json:"column"`
+	EndLine   int         `json:"endLine"`
+	EndColumn int         `json:"endColumn"`
+	Severity  string      `json:"severity"`
+	Code      string      `json:"code"`
+	Message   string      `json:"message"`
+	Notes     []string    `json:"notes,omitempty"`
+	Help      string      `json:"help,omitempty"`
+	Stack     []JSONFrame `json:"stack,omitempty"`
+}
+
+// JSONFrame is a line of the traceback of a runtime error
+type JSONFrame struct {
+	Function string `json:"function"`
+	Line     int    `json:"line"`
+	Column   int    `json:"column"`
+	Repeated int    `json:"repeated,omitempty"`
+}
+
+// NewJSONDiagnostics converts the errors found in the source of fileName.
+// ErrorLists are flattened and errors that can't be placed in the source
+// keep a zero position
+func NewJSONDiagnostics(fileName string, source string, errs []error) []JSONDiagnostic {
+	diagnostics := []JSONDiagnostic{}
+	for _, err := range errs {
+		if list, ok := err.(ErrorList); ok {
+			diagnostics = append(diagnostics, NewJSONDiagnostics(fileName, source, list)...)
+			continue
+		}
+		diagnoser, ok := err.(Diagnoser)
+		if !ok {
+			diagnostics = append(diagnostics, JSONDiagnostic{File: fileName, Severity: SeverityError, Message: err.Error()})
+			continue
+		}
+		d := diagnoser.Diagnostic()
+		endLine, endColumn := position(source, d.Span.End)
+		if d.Span.End <= d.Span.Start {
+			endLine, endColumn = d.Line, d.Column
+		}
+		diagnostics = append(diagnostics, JSONDiagnostic{
+			File:      fileName,
+			Line:      d.Line,
+			Column:    d.Column,
+			EndLine:   endLine,
+			EndColumn: endColumn,
+			Severity:  SeverityError,
+			Code:      d.Code,
+			Message:   d.Message,
+			Notes:     d.Notes,
+			Help:      d.Help,
+			Stack:     jsonFrames(d.Trace),
+		})
+	}
+	return diagnostics
+}
+
+// MarshalJSONDiagnostics encodes the errors as a JSON array
+func MarshalJSONDiagnostics(fileName string, source string, errs []error) ([]byte, error) {
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	// messages quote source such as <nil> which should stay readable
+	encoder.SetEscapeHTML(false)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(NewJSONDiagnostics(fileName, source, errs)); err != nil {
+		return nil, err
+	}
+	return bytes.TrimRight(buf.Bytes(), "\n"), nil
+}
+
+// position finds the line and column of a byte offset in the source.
+// Columns count runes like those of the scanner
+func position(source string, offset int) (int, int) {
+	if offset > len(source) {
+		offset = len(source)
+	}
+	if offset < 0 {
+		offset = 0
+	}
+	before := source[:offset]
+	line := strings.Count(before, "\n") + 1
+	column := utf8.RuneCountInString(before[strings.LastIndex(before, "\n")+1:]) + 1
+	return line, column
+}
+
+// jsonFrames converts a traceback, keeping it nil when there is none
+func jsonFrames(trace []TraceLine) []JSONFrame {
+	var frames []JSONFrame
+	for _, line := range trace {
+		frames = append(frames, JSONFrame{Function: line.Function, Line: line.Line, Column: line.Column, Repeated: line.Repeated})
+	}
+	return frames
+}
@@ -3,12 +3,18 @@ package parseerror
 import (
 	"fmt"
 	"lo/token"
-	"os"
+	"strings"
 )
 
-// HadError stops code execution when we encounter a known error
-var HadError = false
-var HadRunTimeError = false
+// Codes identify each kind of error in the machine-readable diagnostics.
+// They are stable: a code is never reused for a different error
+const (
+	CodeIO      = "E0000"
+	CodeSyntax  = "E0100"
+	CodeParse   = "E0101"
+	CodeResolve = "E0200"
+	CodeRunTime = "E0300"
+)
 
 // SyntaxError describes a syntactic error on a given line
 type SyntaxError struct {
@@ -17,7 +23,6 @@ type SyntaxError struct {
 }
 
 func (e *SyntaxError) Error() string {
-	HadError = true
 	return MakeError(e.Token, e.Message)
 }
 
@@ -28,25 +33,63 @@ type ParseError struct {
 }
 
 func (e *ParseError) Error() string {
-	HadError = true
 	return MakeError(e.Token, e.Message)
 }
 
-// RunTimeError occured when the expression values were being evaluated
+// ResolveError is a static error found by the resolver before the code
+// is executed
+type ResolveError struct {
+	Token   token.Token
+	Message string
+}
+
+func (e *ResolveError) Error() string {
+	return MakeError(e.Token, e.Message)
+}
+
+// RunTimeError occured when the expression values were being evaluated.
+// Stack holds the calls in progress, innermost first
 type RunTimeError struct {
 	Token   token.Token
 	Message string
+	Stack   []Frame
+}
+
+// Frame is a function call in progress: the name of the function and the
+// token of the call site
+type Frame struct {
+	Function string
+	Call     token.Token
 }
 
 func (e *RunTimeError) Error() string {
-	HadRunTimeError = true
-	fmt.Fprintf(os.Stderr, "[line %d] RunTime Error: %s: %s\n", e.Token.Line, e.Token.Lexeme, e.Message)
 	return MakeError(e.Token, e.Message)
 }
 
-// LogError reports an error
-func LogError(err error) error {
-	return fmt.Errorf("%v", err)
+// IOError is a failure to read the source, such as a missing file
+type IOError struct {
+	Err error
+}
+
+func (e *IOError) Error() string {
+	return e.Err.Error()
+}
+
+// Unwrap returns the underlying read error
+func (e *IOError) Unwrap() error {
+	return e.Err
+}
+
+// ErrorList holds several errors reported together i.e. every syntax error
+// in a file
+type ErrorList []error
+
+func (e ErrorList) Error() string {
+	messages := make([]string, 0, len(e))
+	for _, err := range e {
+		messages = append(messages, err.Error())
+	}
+	return strings.Join(messages, "\n")
 }
 
 // MakeError shows a parsing error as a string
@@ -56,9 +99,3 @@ func MakeError(t token.Token, message string) string {
 	}
 	return fmt.Sprintf("[line %v] Error at '%s': %s", t.Line, t.Lexeme, message)
 }
-
-// ReportError shows an error location on the stderr
-func ReportError(line int, message string) {
-	HadError = true
-	fmt.Fprintf(os.Stderr, "[line %d] Error: %s\n", line, message)
-}
@@ -1 +1,209 @@
 package parseerror
+
+import (
+	"errors"
+	"lo/token"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestDiagnostics(t *testing.T) {
+	first := NewDiagnostics()
+	second := NewDiagnostics()
+
+	first.Report(&ParseError{Token: token.Token{Type: token.EOF, Line: 2}, Message: "Expected an expression"})
+	if !first.HadError() || first.HadRunTimeError() {
+		t.Errorf("expected a ParseError to be reported as a static error")
+	}
+	if second.HadError() || len(second.Errors()) != 0 {
+		t.Errorf("expected separate Diagnostics not to share errors")
+	}
+
+	second.Report(&RunTimeError{Token: token.Token{Type: token.MINUS, Lexeme: "-", Line: 3}, Message: "Operand must be a number"})
+	if second.HadError() || !second.HadRunTimeError() {
+		t.Errorf("expected a RunTimeError to be reported as a runtime error")
+	}
+
+	expected := "[line 2] Error at end: Expected an expression"
+	if errs := first.Errors(); len(errs) != 1 || errs[0].Error() != expected {
+		t.Errorf("expected %q but got %v", expected, errs)
+	}
+
+	first.Reset()
+	if first.HadError() || len(first.Errors()) != 0 {
+		t.Errorf("expected Reset to forget the reported errors")
+	}
+}
+
+func TestErrorList(t *testing.T) {
+	errs := ErrorList{
+		&ParseError{Token: token.Token{Type: token.SEMICOLON, Lexeme: ";", Line: 1}, Message: "Expected an expression"},
+		&ParseError{Token: token.Token{Type: token.EOF, Line: 4}, Message: "Expected '}' after block."},
+	}
+	expected := "[line 1] Error at ';': Expected an expression\n[line 4] Error at end: Expected '}' after block."
+	if errs.Error() != expected {
+		t.Errorf("expected %q but got %q", expected, errs.Error())
+	}
+}
+
+func TestRenderer(t *testing.T) {
+	source := "var a = 1;\n\tprint a + \"x\";\nprint"
+	renderer := NewRenderer("main.lo", source, false)
+
+	plus := token.Token{Type: token.PLUS, Lexeme: "+", Line: 2, Column: 10, Offset: 20, EndOffset: 21}
+	runTimeError := &RunTimeError{Token: plus, Message: "Operands must be numbers."}
+	expected := "error: Operands must be numbers.\n" +
+		" --> main.lo:2:10\n" +
+		"  |\n" +
+		"2 | \tprint a + \"x\";\n" +
+		"  | \t        ^\n"
+	if got := renderer.Render(runTimeError); got != expected {
+		t.Errorf("expected\n%s\nbut got\n%s", expected, got)
+	}
+
+	eof := token.Token{Type: token.EOF, Line: 3, Column: 6, Offset: 31, EndOffset: 31}
+	parseError := &ParseError{Token: eof, Message: "Expected an expression"}
+	expected = "error: Expected an expression\n" +
+		" --> main.lo:3:6\n" +
+		"  |\n" +
+		"3 | print\n" +
+		"  |      ^\n"
+	if got := renderer.Render(parseError); got != expected {
+		t.Errorf("expected\n%s\nbut got\n%s", expected, got)
+	}
+}
+
+func TestRendererTraceback(t *testing.T) {
+	source := "fun f() {\n  return 1 + nil;\n}\nf();"
+	plus := token.Token{Type: token.PLUS, Lexeme: "+", Line: 2, Column: 12, Offset: 21, EndOffset: 22}
+	paren := token.Token{Type: token.RIGHTPAREN, Lexeme: ")", Line: 4, Column: 3, Offset: 34, EndOffset: 35}
+	err := &RunTimeError{Token: plus, Message: "Operands must be numbers.", Stack: []Frame{{Function: "f", Call: paren}}}
+
+	expected := "error: Operands must be numbers.\n" +
+		" --> main.lo:2:12\n" +
+		"  |\n" +
+		"2 |   return 1 + nil;\n" +
+		"  |            ^\n" +
+		"  = traceback:\n" +
+		"      at f (main.lo:2)\n" +
+		"      at <script> (main.lo:4)\n"
+	if got := NewRenderer("main.lo", source, false).Render(err); got != expected {
+		t.Errorf("expected\n%s\nbut got\n%s", expected, got)
+	}
+}
+
+func TestRendererNotesAndColour(t *testing.T) {
+	source := "fun f() { return x; }"
+	x := token.Token{Type: token.IDENTIFIER, Lexeme: "x", Line: 1, Column: 18, Offset: 17, EndOffset: 18}
+	err := &diagnosedError{Diagnostic{
+		Message: "Undefined variable 'x'.",
+		Line:    x.Line,
+		Column:  x.Column,
+		Span:    token.SpanOf(x),
+		Notes:   []string{"called from here"},
+		Help:    "declare it with var",
+	}}
+
+	expected := "error: Undefined variable 'x'.\n" +
+		" --> main.lo:1:18\n" +
+		"  |\n" +
+		"1 | fun f() { return x; }\n" +
+		"  |                  ^\n" +
+		"  = note: called from here\n" +
+		"  = help: declare it with var\n"
+	if got := NewRenderer("main.lo", source, false).Render(err); got != expected {
+		t.Errorf("expected\n%s\nbut got\n%s", expected, got)
+	}
+
+	coloured := NewRenderer("main.lo", source, true).Render(err)
+	if !strings.Contains(coloured, colourError+"error"+colourReset) {
+		t.Errorf("expected the coloured output to paint the severity but got %q", coloured)
+	}
+}
+
+// diagnosedError is a Diagnoser with a fixed Diagnostic
+type diagnosedError struct {
+	diagnostic Diagnostic
+}
+
+func (e *diagnosedError) Error() string {
+	return e.diagnostic.Message
+}
+
+func (e *diagnosedError) Diagnostic() Diagnostic {
+	return e.diagnostic
+}
+
+func TestJSONDiagnostics(t *testing.T) {
+	source := "var a = 1;\nprint a +\n"
+	eof := token.Token{Type: token.EOF, Line: 3, Column: 1, Offset: 21, EndOffset: 21}
+	name := token.Token{Type: token.IDENTIFIER, Lexeme: "a", Line: 2, Column: 7, Offset: 17, EndOffset: 18}
+	errs := []error{
+		ErrorList{&ParseError{Token: eof, Message: "Expected an expression"}},
+		&RunTimeError{Token: name, Message: "Undefined variable 'a'."},
+		&IOError{Err: errors.New("no such file")},
+	}
+
+	got := NewJSONDiagnostics("main.lo", source, errs)
+	expected := []JSONDiagnostic{
+		{File: "main.lo", Line: 3, Column: 1, EndLine: 3, EndColumn: 1, Severity: SeverityError, Code: CodeParse, Message: "Expected an expression"},
+		{File: "main.lo", Line: 2, Column: 7, EndLine: 2, EndColumn: 8, Severity: SeverityError, Code: CodeRunTime, Message: "Undefined variable 'a'."},
+		{File: "main.lo", Severity: SeverityError, Code: CodeIO, Message: "no such file"},
+	}
+	if !reflect.DeepEqual(got, expected) {
+		t.Fatalf("expected %+v but got %+v", expected, got)
+	}
+
+	output, err := MarshalJSONDiagnostics("main.lo", source, nil)
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	if string(output) != "[]" {
+		t.Errorf("expected no errors to give an empty array but got %s", output)
+	}
+}
+
+func TestRendererUnicode(t *testing.T) {
+	source := "var café = \"☕\" - 1;"
+	minus := token.Token{Type: token.MINUS, Lexeme: "-", Line: 1, Column: 16, Offset: 20, EndOffset: 21}
+	str := token.Token{Type: token.STRING, Lexeme: "\"☕\"", Line: 1, Column: 12, Offset: 12, EndOffset: 17}
+
+	expected := "error: Operands must be numbers.\n" +
+		" --> main.lo:1:16\n" +
+		"  |\n" +
+		"1 | var café = \"☕\" - 1;\n" +
+		"  |                ^\n"
+	renderer := NewRenderer("main.lo", source, false)
+	if got := renderer.Render(&RunTimeError{Token: minus, Message: "Operands must be numbers."}); got != expected {
+		t.Errorf("expected\n%s\nbut got\n%s", expected, got)
+	}
+	if got := renderer.Render(&ParseError{Token: str, Message: "here"}); !strings.HasSuffix(got, "  |            ^^^\n") {
+		t.Errorf("expected the string to be underlined with one caret per rune but got\n%s", got)
+	}
+
+	got := NewJSONDiagnostics("main.lo", source, []error{&ParseError{Token: str, Message: "here"}})
+	if got[0].Column != 12 || got[0].EndColumn != 15 {
+		t.Errorf("expected the JSON columns to count runes but got %d to %d", got[0].Column, got[0].EndColumn)
+	}
+}
+
+func TestRendererWithoutSource(t *testing.T) {
+	eof := token.Token{Type: token.EOF, Line: 1, Column: 7, Offset: 6, EndOffset: 6}
+	got := NewRenderer("<stdin>", "", false).Render(&ParseError{Token: eof, Message: "Expected an expression"})
+	expected := "error: Expected an expression\n --> <stdin>:1:7\n"
+	if got != expected {
+		t.Errorf("expected\n%s\nbut got\n%s", expected, got)
+	}
+}
+
+func TestRenderIOError(t *testing.T) {
+	err := &IOError{Err: errors.New("open main.lo: no such file or directory")}
+	expected := "error: open main.lo: no such file or directory\n --> main.lo\n"
+	if got := NewRenderer("main.lo", "", false).Render(err); got != expected {
+		t.Errorf("expected\n%s\nbut got\n%s", expected, got)
+	}
+	if !errors.Is(err, err.Err) {
+		t.Errorf("expected the IOError to unwrap to the read error")
+	}
+}
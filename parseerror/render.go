@@ -0,0 +1,146 @@
+package parseerror
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// ANSI escape codes used when rendering in colour
+const (
+	colourReset = "\x1b[0m"
+	colourError = "\x1b[1;31m"
+	colourBold  = "\x1b[1m"
+	colourBlue  = "\x1b[1;34m"
+	colourHelp  = "\x1b[1;36m"
+)
+
+// Renderer shows errors the way a compiler does: the message, the
+// offending source line and a caret underline below the exact span,
+// followed by any traceback, notes and help
+type Renderer struct {
+	fileName string
+	source   string
+	lines    []string
+	colour   bool
+}
+
+// NewRenderer creates a Renderer for errors in the source of fileName.
+// colour turns on ANSI colours and should only be set for terminals. An
+// empty source, as for a stream that was not kept, shows no source lines
+func NewRenderer(fileName string, source string, colour bool) *Renderer {
+	var lines []string
+	if source != "" {
+		lines = strings.Split(source, "\n")
+	}
+	return &Renderer{fileName: fileName, source: source, lines: lines, colour: colour}
+}
+
+// Render formats an error. Errors that can't be placed in the source are
+// shown as they are and ErrorLists are rendered one error after another
+func (r *Renderer) Render(err error) string {
+	if errs, ok := err.(ErrorList); ok {
+		rendered := make([]string, 0, len(errs))
+		for _, e := range errs {
+			rendered = append(rendered, r.Render(e))
+		}
+		return strings.Join(rendered, "\n")
+	}
+	diagnoser, ok := err.(Diagnoser)
+	if !ok {
+		return r.paint(colourError, "error") + r.paint(colourBold, ": "+err.Error()) + "\n"
+	}
+	return r.renderDiagnostic(diagnoser.Diagnostic())
+}
+
+// renderDiagnostic lays out a single placed diagnostic
+func (r *Renderer) renderDiagnostic(d Diagnostic) string {
+	var sb strings.Builder
+	lineNumber := strconv.Itoa(d.Line)
+	gutter := strings.Repeat(" ", len(lineNumber))
+
+	sb.WriteString(r.paint(colourError, "error"))
+	sb.WriteString(r.paint(colourBold, ": "+d.Message))
+	sb.WriteString("\n")
+	sb.WriteString(gutter)
+	sb.WriteString(r.paint(colourBlue, "--> "))
+	if d.Line == 0 {
+		// errors such as an unreadable file have no position
+		sb.WriteString(r.fileName + "\n")
+	} else {
+		sb.WriteString(fmt.Sprintf("%s:%d:%d\n", r.fileName, d.Line, d.Column))
+	}
+
+	if d.Line >= 1 && d.Line <= len(r.lines) {
+		line := strings.TrimRight(r.lines[d.Line-1], "\r")
+		sb.WriteString(gutter + r.paint(colourBlue, " |") + "\n")
+		sb.WriteString(r.paint(colourBlue, lineNumber+" | "))
+		sb.WriteString(line)
+		sb.WriteString("\n")
+		sb.WriteString(gutter + r.paint(colourBlue, " | "))
+		sb.WriteString(r.underline(line, d))
+		sb.WriteString("\n")
+	}
+
+	if len(d.Trace) > 0 {
+		sb.WriteString(gutter + r.paint(colourBlue, " = ") + r.paint(colourBold, "traceback") + ":\n")
+		for _, line := range d.Trace {
+			sb.WriteString(fmt.Sprintf("%s     at %s (%s:%d)", gutter, line.Function, r.fileName, line.Line))
+			if line.Repeated > 0 {
+				sb.WriteString(fmt.Sprintf(" [repeated %d more times]", line.Repeated))
+			}
+			sb.WriteString("\n")
+		}
+	}
+	for _, note := range d.Notes {
+		sb.WriteString(gutter + r.paint(colourBlue, " = ") + r.paint(colourBold, "note") + ": " + note + "\n")
+	}
+	if d.Help != "" {
+		sb.WriteString(gutter + r.paint(colourBlue, " = ") + r.paint(colourHelp, "help") + ": " + d.Help + "\n")
+	}
+	return sb.String()
+}
+
+// underline builds the caret line below the span. Columns count runes and
+// tabs before the span are kept so the carets line up with the source
+// above them. Spans that run over several lines are underlined up to the
+// end of the first one
+func (r *Renderer) underline(line string, d Diagnostic) string {
+	chars := []rune(line)
+	start := d.Column - 1
+	if start < 0 {
+		start = 0
+	}
+	if start > len(chars) {
+		start = len(chars)
+	}
+	var padding strings.Builder
+	for _, char := range chars[:start] {
+		if char == '\t' {
+			padding.WriteRune('\t')
+		} else {
+			padding.WriteRune(' ')
+		}
+	}
+
+	width := 0
+	if d.Span.Start >= 0 && d.Span.Start <= d.Span.End && d.Span.End <= len(r.source) {
+		width = utf8.RuneCountInString(r.source[d.Span.Start:d.Span.End])
+	}
+	if start+width > len(chars) {
+		width = len(chars) - start
+	}
+	if width < 1 {
+		width = 1
+	}
+	return padding.String() + r.paint(colourError, strings.Repeat("^", width))
+}
+
+// paint wraps text in an ANSI colour when colours are on
+func (r *Renderer) paint(colour string, text string) string {
+	if !r.colour {
+		return text
+	}
+	return colour + text + colourReset
+}
@@ -6,34 +6,103 @@ import (
 	"lo/token"
 )
 
-// Parser consumes Tokens and uses current to point to the next Token position
+// maxArguments is the most arguments or parameters a function can have
+const maxArguments = 255
+
+// TokenSource hands out tokens one at a time, as the scanner.Scanner does.
+// It returns an error in place of a token for bad input and an EOF token
+// at the end
+type TokenSource interface {
+	Next() (token.Token, error)
+}
+
+// Parser pulls Tokens from source as it needs them. current is the next
+// Token and previous the one just consumed
 type Parser struct {
-	tokens  []token.Token
-	current int64
-	inloop  bool
+	source            TokenSource
+	current, previous token.Token
+	inloop            bool
+	errors            parseerror.ErrorList
+	diagnostics       *parseerror.Diagnostics
+}
+
+// NewParser creates a new parser over scanned tokens that reports errors
+// to diagnostics
+func NewParser(tokens []token.Token, diagnostics *parseerror.Diagnostics) *Parser {
+	return NewStreamParser(&tokenSlice{tokens: tokens}, diagnostics)
+}
+
+// NewStreamParser creates a new parser that consumes the tokens of source
+// lazily. Errors from source are already reported so they are only added
+// to the ErrorList returned by Parse
+func NewStreamParser(source TokenSource, diagnostics *parseerror.Diagnostics) *Parser {
+	p := &Parser{source: source, diagnostics: diagnostics}
+	p.current = p.next()
+	return p
 }
 
-// NewParser creates a new parser
-func NewParser(tokens []token.Token) *Parser {
-	return &Parser{tokens, 0, false}
+// tokenSlice is a TokenSource over tokens that were all scanned up front
+type tokenSlice struct {
+	tokens []token.Token
+	next   int
 }
 
-// Parse an expression
+// Next returns the next token of the slice and EOF once it runs out
+func (t *tokenSlice) Next() (token.Token, error) {
+	if t.next >= len(t.tokens) {
+		if len(t.tokens) > 0 && t.tokens[len(t.tokens)-1].Type == token.EOF {
+			return t.tokens[len(t.tokens)-1], nil
+		}
+		return token.Token{Type: token.EOF}, nil
+	}
+	t.next++
+	return t.tokens[t.next-1], nil
+}
+
+// Parse the tokens into statements. Parsing carries on past syntax errors
+// so that all of them are found in one go. The statements that parsed
+// fine are returned along with an ErrorList of every syntax error
 func (p *Parser) Parse() ([]ast.Stmt, error) {
 	stmts := make([]ast.Stmt, 0)
 	for !p.isAtEnd() {
-		stmt, err := p.declaration()
-		if err != nil {
-			return nil, err
+		if stmt := p.declaration(); stmt != nil {
+			stmts = append(stmts, stmt)
 		}
-		stmts = append(stmts, stmt)
+	}
+	if len(p.errors) > 0 {
+		return stmts, p.errors
 	}
 	return stmts, nil
 }
 
+// error records and reports a syntax error
+func (p *Parser) error(err error) {
+	p.errors = append(p.errors, err)
+	p.diagnostics.Report(err)
+}
+
 // declaration repeatedly gets called when parsing a series of
-// statements in a block
-func (p *Parser) declaration() (ast.Stmt, error) {
+// statements in a block. A declaration with a syntax error is recorded,
+// skipped up to the next statement and returned as nil
+func (p *Parser) declaration() ast.Stmt {
+	stmt, err := p.parseDeclaration()
+	if err != nil {
+		p.error(err)
+		p.synchronize()
+		return nil
+	}
+	return stmt
+}
+
+// parseDeclaration parses a class, function or variable declaration or
+// falls through to a statement
+func (p *Parser) parseDeclaration() (ast.Stmt, error) {
+	if p.match(token.CLASS) {
+		return p.classDeclaration()
+	}
+	if p.match(token.FUN) {
+		return p.function("function")
+	}
 	if p.match(token.VAR) {
 		decl, err := p.varDeclaration()
 		if err != nil {
@@ -41,17 +110,27 @@ func (p *Parser) declaration() (ast.Stmt, error) {
 		}
 		return decl, nil
 	}
-	stmt, err := p.statement()
-	if e, ok := err.(*parseerror.ParseError); ok {
-		p.synchronize()
-		return nil, e
-	}
-	return stmt, nil
+	return p.statement()
 }
 
 // statement determines the specific statement rule matched
 // by looking at the token
 func (p *Parser) statement() (ast.Stmt, error) {
+	if p.match(token.FOR) {
+		return p.forStatement()
+	}
+	if p.match(token.IF) {
+		return p.ifStatement()
+	}
+	if p.match(token.WHILE) {
+		return p.whileStatement()
+	}
+	if p.match(token.RETURN) {
+		return p.returnStatement()
+	}
+	if p.match(token.BREAK, token.CONTINUE) {
+		return p.loopControlStatement()
+	}
 	if p.match(token.PRINT) {
 		stmt, err := p.printStatement()
 		if err != nil {
@@ -59,6 +138,14 @@ func (p *Parser) statement() (ast.Stmt, error) {
 		}
 		return stmt, nil
 	}
+	if p.match(token.LEFTBRACE) {
+		brace := p.previous
+		stmts, err := p.block()
+		if err != nil {
+			return nil, err
+		}
+		return &ast.BlockStmt{Node: p.nodeFrom(brace), Statements: stmts}, nil
+	}
 	expr, err := p.expressionStatement()
 	if err != nil {
 		return nil, err
@@ -66,8 +153,252 @@ func (p *Parser) statement() (ast.Stmt, error) {
 	return expr, nil
 }
 
+// block parses the declarations up to the closing brace of a block
+func (p *Parser) block() ([]ast.Stmt, error) {
+	stmts := make([]ast.Stmt, 0)
+	for !p.check(token.RIGHTBRACE) && !p.isAtEnd() {
+		if stmt := p.declaration(); stmt != nil {
+			stmts = append(stmts, stmt)
+		}
+	}
+	if _, err := p.consume(token.RIGHTBRACE, "Expected '}' after block."); err != nil {
+		return nil, err
+	}
+	return stmts, nil
+}
+
+// ifStatement parses the condition and the branches of an if statement
+func (p *Parser) ifStatement() (ast.Stmt, error) {
+	keyword := p.previous
+	if _, err := p.consume(token.LEFTPAREN, "Expected '(' after 'if'."); err != nil {
+		return nil, err
+	}
+	condition, err := p.expression()
+	if err != nil {
+		return nil, err
+	}
+	if _, err := p.consume(token.RIGHTPAREN, "Expected ')' after if condition."); err != nil {
+		return nil, err
+	}
+	thenBranch, err := p.statement()
+	if err != nil {
+		return nil, err
+	}
+	var elseBranch ast.Stmt
+	if p.match(token.ELSE) {
+		elseBranch, err = p.statement()
+		if err != nil {
+			return nil, err
+		}
+	}
+	return &ast.IfStmt{Node: p.nodeFrom(keyword), Condition: condition, ThenBranch: thenBranch, ElseBranch: elseBranch}, nil
+}
+
+// whileStatement parses the condition and the body of a while loop
+func (p *Parser) whileStatement() (ast.Stmt, error) {
+	keyword := p.previous
+	if _, err := p.consume(token.LEFTPAREN, "Expected '(' after 'while'."); err != nil {
+		return nil, err
+	}
+	condition, err := p.expression()
+	if err != nil {
+		return nil, err
+	}
+	if _, err := p.consume(token.RIGHTPAREN, "Expected ')' after condition."); err != nil {
+		return nil, err
+	}
+	body, err := p.loopBody()
+	if err != nil {
+		return nil, err
+	}
+	return &ast.WhileStmt{Node: p.nodeFrom(keyword), Condition: condition, Body: body}, nil
+}
+
+// forStatement desugars a C-style for loop into a while loop wrapped in
+// a block that holds the initializer. The increment stays on the while
+// loop so that continue still runs it
+func (p *Parser) forStatement() (ast.Stmt, error) {
+	keyword := p.previous
+	if _, err := p.consume(token.LEFTPAREN, "Expected '(' after 'for'."); err != nil {
+		return nil, err
+	}
+
+	var initializer ast.Stmt
+	var err error
+	if p.match(token.SEMICOLON) {
+		// no initializer
+	} else if p.match(token.VAR) {
+		initializer, err = p.varDeclaration()
+	} else {
+		initializer, err = p.expressionStatement()
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var condition ast.Expr
+	if !p.check(token.SEMICOLON) {
+		condition, err = p.expression()
+		if err != nil {
+			return nil, err
+		}
+	}
+	if _, err := p.consume(token.SEMICOLON, "Expected ';' after loop condition."); err != nil {
+		return nil, err
+	}
+
+	var increment ast.Expr
+	if !p.check(token.RIGHTPAREN) {
+		increment, err = p.expression()
+		if err != nil {
+			return nil, err
+		}
+	}
+	if _, err := p.consume(token.RIGHTPAREN, "Expected ')' after for clauses."); err != nil {
+		return nil, err
+	}
+
+	body, err := p.loopBody()
+	if err != nil {
+		return nil, err
+	}
+	if condition == nil {
+		condition = &ast.LiteralExpr{Node: ast.Node{Range: token.SpanOf(keyword)}, Object: true}
+	}
+	body = &ast.WhileStmt{Node: p.nodeFrom(keyword), Condition: condition, Body: body, Increment: increment}
+	if initializer != nil {
+		body = &ast.BlockStmt{Node: p.nodeFrom(keyword), Statements: []ast.Stmt{initializer, body}}
+	}
+	return body, nil
+}
+
+// loopBody parses the body of a loop, the only place break and continue
+// are allowed
+func (p *Parser) loopBody() (ast.Stmt, error) {
+	enclosing := p.inloop
+	p.inloop = true
+	defer func() {
+		p.inloop = enclosing
+	}()
+	return p.statement()
+}
+
+// loopControlStatement parses a break or continue, reporting it when it
+// is not inside a loop body
+func (p *Parser) loopControlStatement() (ast.Stmt, error) {
+	keyword := p.previous
+	if !p.inloop {
+		return nil, &parseerror.ParseError{Token: keyword, Message: "Can't use '" + keyword.Lexeme + "' outside of a loop."}
+	}
+	if _, err := p.consume(token.SEMICOLON, "Expected ';' after '"+keyword.Lexeme+"'."); err != nil {
+		return nil, err
+	}
+	if keyword.Type == token.BREAK {
+		return &ast.BreakStmt{Node: p.nodeFrom(keyword), Keyword: keyword}, nil
+	}
+	return &ast.ContinueStmt{Node: p.nodeFrom(keyword), Keyword: keyword}, nil
+}
+
+// classDeclaration parses the name and the methods of a class
+func (p *Parser) classDeclaration() (ast.Stmt, error) {
+	keyword := p.previous
+	name, err := p.consume(token.IDENTIFIER, "Expected class name.")
+	if err != nil {
+		return nil, err
+	}
+	var superclass *ast.VariableExpr
+	if p.match(token.LESS) {
+		if _, err := p.consume(token.IDENTIFIER, "Expected superclass name."); err != nil {
+			return nil, err
+		}
+		superclass = &ast.VariableExpr{Node: p.nodeFrom(p.previous), Name: p.previous}
+	}
+	if _, err := p.consume(token.LEFTBRACE, "Expected '{' before class body."); err != nil {
+		return nil, err
+	}
+	methods := make([]*ast.FunctionStmt, 0)
+	for !p.check(token.RIGHTBRACE) && !p.isAtEnd() {
+		method, err := p.function("method")
+		if err != nil {
+			return nil, err
+		}
+		methods = append(methods, method.(*ast.FunctionStmt))
+	}
+	if _, err := p.consume(token.RIGHTBRACE, "Expected '}' after class body."); err != nil {
+		return nil, err
+	}
+	return &ast.ClassStmt{Node: p.nodeFrom(keyword), Name: name, Superclass: superclass, Methods: methods}, nil
+}
+
+// function parses the name, parameters and body of a function of the
+// given kind
+func (p *Parser) function(kind string) (ast.Stmt, error) {
+	// a function starts at the fun keyword, a method at its name
+	start := p.peek()
+	if kind == "function" {
+		start = p.previous
+	}
+	name, err := p.consume(token.IDENTIFIER, "Expected "+kind+" name.")
+	if err != nil {
+		return nil, err
+	}
+	if _, err := p.consume(token.LEFTPAREN, "Expected '(' after "+kind+" name."); err != nil {
+		return nil, err
+	}
+	params := make([]token.Token, 0)
+	if !p.check(token.RIGHTPAREN) {
+		for {
+			if len(params) >= maxArguments {
+				return nil, &parseerror.ParseError{Token: p.peek(), Message: "Can't have more than 255 parameters."}
+			}
+			param, err := p.consume(token.IDENTIFIER, "Expected parameter name.")
+			if err != nil {
+				return nil, err
+			}
+			params = append(params, param)
+			if !p.match(token.COMMA) {
+				break
+			}
+		}
+	}
+	if _, err := p.consume(token.RIGHTPAREN, "Expected ')' after parameters."); err != nil {
+		return nil, err
+	}
+	if _, err := p.consume(token.LEFTBRACE, "Expected '{' before "+kind+" body."); err != nil {
+		return nil, err
+	}
+	// a loop around the declaration does not make break or continue valid
+	// inside the function body
+	enclosing := p.inloop
+	p.inloop = false
+	body, err := p.block()
+	p.inloop = enclosing
+	if err != nil {
+		return nil, err
+	}
+	return &ast.FunctionStmt{Node: p.nodeFrom(start), Name: name, Params: params, Body: body}, nil
+}
+
+// returnStatement parses a return with an optional value
+func (p *Parser) returnStatement() (ast.Stmt, error) {
+	keyword := p.previous
+	var value ast.Expr
+	var err error
+	if !p.check(token.SEMICOLON) {
+		value, err = p.expression()
+		if err != nil {
+			return nil, err
+		}
+	}
+	if _, err := p.consume(token.SEMICOLON, "Expected ';' after return value."); err != nil {
+		return nil, err
+	}
+	return &ast.ReturnStmt{Node: p.nodeFrom(keyword), Keyword: keyword, Value: value}, nil
+}
+
 // varDeclaration
 func (p *Parser) varDeclaration() (ast.Stmt, error) {
+	keyword := p.previous
 	typ, err := p.consume(token.IDENTIFIER, "Expected a variable name.")
 	if err != nil {
 		return nil, err
@@ -80,18 +411,23 @@ func (p *Parser) varDeclaration() (ast.Stmt, error) {
 			return nil, err
 		}
 	}
-	p.consume(token.SEMICOLON, "Expected a ';' after a variable declaration")
-	return &ast.VarStmt{Name: typ, Initializer: initializer}, nil
+	if _, err := p.consume(token.SEMICOLON, "Expected a ';' after a variable declaration"); err != nil {
+		return nil, err
+	}
+	return &ast.VarStmt{Node: p.nodeFrom(keyword), Name: typ, Initializer: initializer}, nil
 }
 
 // printStatement ...
 func (p *Parser) printStatement() (ast.Stmt, error) {
+	keyword := p.previous
 	value, err := p.expression()
 	if err != nil {
 		return nil, err
 	}
-	p.consume(token.SEMICOLON, "Expected ';' after value.")
-	return &ast.PrintStmt{Expression: value}, nil
+	if _, err := p.consume(token.SEMICOLON, "Expected ';' after value."); err != nil {
+		return nil, err
+	}
+	return &ast.PrintStmt{Node: p.nodeFrom(keyword), Expression: value}, nil
 }
 
 // expressionStatement ...
@@ -100,8 +436,10 @@ func (p *Parser) expressionStatement() (ast.Stmt, error) {
 	if err != nil {
 		return nil, err
 	}
-	p.consume(token.SEMICOLON, "Expected ';' after value.")
-	return &ast.ExpressionStmt{Expression: value}, nil
+	if _, err := p.consume(token.SEMICOLON, "Expected ';' after value."); err != nil {
+		return nil, err
+	}
+	return &ast.ExpressionStmt{Node: p.nodeFromExpr(value), Expression: value}, nil
 }
 
 // expression expands to equality rule
@@ -114,6 +452,63 @@ func (p *Parser) expression() (ast.Expr, error) {
 	return p.assignment()
 }
 
+// conditional handles the right associative cond ? a : b expressions
+func (p *Parser) conditional() (ast.Expr, error) {
+	expr, err := p.or()
+	if err != nil {
+		return nil, err
+	}
+	if p.match(token.QMARK) {
+		thenBranch, err := p.expression()
+		if err != nil {
+			return nil, err
+		}
+		if _, err := p.consume(token.COLON, "Expected ':' after then branch of conditional expression."); err != nil {
+			return nil, err
+		}
+		elseBranch, err := p.conditional()
+		if err != nil {
+			return nil, err
+		}
+		expr = &ast.ConditionalExpr{Node: p.nodeFromExpr(expr), Condition: expr, ThenBranch: thenBranch, ElseBranch: elseBranch}
+	}
+	return expr, nil
+}
+
+// or handles the or logical expressions
+func (p *Parser) or() (ast.Expr, error) {
+	expr, err := p.and()
+	if err != nil {
+		return nil, err
+	}
+	for p.match(token.OR) {
+		operator := p.previous
+		right, err := p.and()
+		if err != nil {
+			return nil, err
+		}
+		expr = &ast.LogicalExpr{Node: p.nodeFromExpr(expr), Left: expr, Operator: operator, Right: right}
+	}
+	return expr, nil
+}
+
+// and handles the and logical expressions
+func (p *Parser) and() (ast.Expr, error) {
+	expr, err := p.equality()
+	if err != nil {
+		return nil, err
+	}
+	for p.match(token.AND) {
+		operator := p.previous
+		right, err := p.equality()
+		if err != nil {
+			return nil, err
+		}
+		expr = &ast.LogicalExpr{Node: p.nodeFromExpr(expr), Left: expr, Operator: operator, Right: right}
+	}
+	return expr, nil
+}
+
 // equalilty handles the != and == expressions
 func (p *Parser) equality() (ast.Expr, error) {
 	expr, err := p.comparison()
@@ -122,16 +517,28 @@ func (p *Parser) equality() (ast.Expr, error) {
 	}
 
 	for p.match(token.BANGEQUAL, token.EQUALEQUAL) {
-		operator := p.previous()
+		operator := p.previous
 		right, err := p.comparison()
 		if err != nil {
 			return nil, err
 		}
-		expr = &ast.BinaryExpr{Left: expr, Operator: operator, Right: right}
+		expr = &ast.BinaryExpr{Node: p.nodeFromExpr(expr), Left: expr, Operator: operator, Right: right}
 	}
 	return expr, nil
 }
 
+// nodeFrom records a node spanning from the start token to the end of the
+// token most recently consumed
+func (p *Parser) nodeFrom(start token.Token) ast.Node {
+	return ast.Node{Range: token.SpanOf(start).Join(token.SpanOf(p.previous))}
+}
+
+// nodeFromExpr records a node spanning from the start of expr to the end of
+// the token most recently consumed
+func (p *Parser) nodeFromExpr(expr ast.Expr) ast.Node {
+	return ast.Node{Range: expr.Span().Join(token.SpanOf(p.previous))}
+}
+
 // match returns true if current token is any of the given types
 func (p *Parser) match(types ...token.Type) bool {
 	for _, typ := range types {
@@ -151,13 +558,26 @@ func (p *Parser) check(typ token.Type) bool {
 	return p.peek().Type == typ
 }
 
-// advance looks at the current token and moves the pointer ahead if it's
-// of the type required
+// advance consumes the current token and pulls the next one from the
+// source
 func (p *Parser) advance() token.Token {
 	if !p.isAtEnd() {
-		p.current++
+		p.previous = p.current
+		p.current = p.next()
+	}
+	return p.previous
+}
+
+// next pulls a token from the source, skipping over and recording the
+// errors it returns
+func (p *Parser) next() token.Token {
+	for {
+		tok, err := p.source.Next()
+		if err == nil {
+			return tok
+		}
+		p.errors = append(p.errors, err)
 	}
-	return p.previous()
 }
 
 // isAtEnd checks if you've run out of tokens to parse
@@ -167,12 +587,7 @@ func (p *Parser) isAtEnd() bool {
 
 // peek returns the Token at the current position
 func (p *Parser) peek() token.Token {
-	return p.tokens[p.current]
-}
-
-// previous returns the Token recently consumed
-func (p *Parser) previous() token.Token {
-	return p.tokens[p.current-1]
+	return p.current
 }
 
 // comparison handles the >, >=, < and <= expressions
@@ -182,12 +597,12 @@ func (p *Parser) comparison() (ast.Expr, error) {
 		return nil, err
 	}
 	for p.match(token.GREATER, token.GREATEREQUAL, token.LESS, token.LESSEQUAL) {
-		operator := p.previous()
+		operator := p.previous
 		right, err := p.addition()
 		if err != nil {
 			return nil, err
 		}
-		expr = &ast.BinaryExpr{Left: expr, Operator: operator, Right: right}
+		expr = &ast.BinaryExpr{Node: p.nodeFromExpr(expr), Left: expr, Operator: operator, Right: right}
 	}
 	return expr, nil
 }
@@ -199,12 +614,12 @@ func (p *Parser) addition() (ast.Expr, error) {
 		return nil, err
 	}
 	for p.match(token.MINUS, token.PLUS) {
-		operator := p.previous()
+		operator := p.previous
 		right, err := p.multiplication()
 		if err != nil {
 			return nil, err
 		}
-		expr = &ast.BinaryExpr{Left: expr, Operator: operator, Right: right}
+		expr = &ast.BinaryExpr{Node: p.nodeFromExpr(expr), Left: expr, Operator: operator, Right: right}
 	}
 	return expr, nil
 }
@@ -216,12 +631,12 @@ func (p *Parser) multiplication() (ast.Expr, error) {
 		return nil, err
 	}
 	for p.match(token.SLASH, token.STAR) {
-		operator := p.previous()
+		operator := p.previous
 		right, err := p.unary()
 		if err != nil {
 			return nil, err
 		}
-		expr = &ast.BinaryExpr{Left: expr, Operator: operator, Right: right}
+		expr = &ast.BinaryExpr{Node: p.nodeFromExpr(expr), Left: expr, Operator: operator, Right: right}
 	}
 	return expr, nil
 }
@@ -229,45 +644,130 @@ func (p *Parser) multiplication() (ast.Expr, error) {
 // unary handles the ! and - expressions
 func (p *Parser) unary() (ast.Expr, error) {
 	if p.match(token.BANG, token.MINUS) {
-		operator := p.previous()
+		operator := p.previous
+		right, err := p.unary()
+		if err != nil {
+			return nil, err
+		}
+		return &ast.UnaryExpr{Node: p.nodeFrom(operator), Operator: operator, Right: right}, nil
+	}
+	expr, err := p.power()
+	if err != nil {
+		return nil, err
+	}
+	return expr, nil
+}
+
+// power handles the right associative ** expressions. The exponent may be
+// a unary expression but the base may not, so -2 ** 2 is -(2 ** 2)
+func (p *Parser) power() (ast.Expr, error) {
+	expr, err := p.call()
+	if err != nil {
+		return nil, err
+	}
+	if p.match(token.POWER) {
+		operator := p.previous
 		right, err := p.unary()
 		if err != nil {
 			return nil, err
 		}
-		return &ast.UnaryExpr{Operator: operator, Right: right}, nil
+		expr = &ast.BinaryExpr{Node: p.nodeFromExpr(expr), Left: expr, Operator: operator, Right: right}
 	}
+	return expr, nil
+}
+
+// call handles function calls on a primary expression
+func (p *Parser) call() (ast.Expr, error) {
 	expr, err := p.primary()
 	if err != nil {
 		return nil, err
 	}
+	for {
+		if p.match(token.LEFTPAREN) {
+			expr, err = p.finishCall(expr)
+			if err != nil {
+				return nil, err
+			}
+		} else if p.match(token.DOT) {
+			name, err := p.consume(token.IDENTIFIER, "Expected property name after '.'.")
+			if err != nil {
+				return nil, err
+			}
+			expr = &ast.GetExpr{Node: p.nodeFromExpr(expr), Expression: expr, Name: name}
+		} else {
+			break
+		}
+	}
 	return expr, nil
 }
 
+// finishCall parses the arguments of a call up to the closing parenthesis
+func (p *Parser) finishCall(callee ast.Expr) (ast.Expr, error) {
+	arguments := make([]ast.Expr, 0)
+	if !p.check(token.RIGHTPAREN) {
+		for {
+			if len(arguments) >= maxArguments {
+				return nil, &parseerror.ParseError{Token: p.peek(), Message: "Can't have more than 255 arguments."}
+			}
+			argument, err := p.expression()
+			if err != nil {
+				return nil, err
+			}
+			arguments = append(arguments, argument)
+			if !p.match(token.COMMA) {
+				break
+			}
+		}
+	}
+	paren, err := p.consume(token.RIGHTPAREN, "Expected ')' after arguments.")
+	if err != nil {
+		return nil, err
+	}
+	return &ast.CallExpr{Node: p.nodeFromExpr(callee), Callee: callee, Paren: paren, Arguments: arguments}, nil
+}
+
 // primary is the highest level of precedence handling the basic expressions
 func (p *Parser) primary() (ast.Expr, error) {
 	if p.match(token.FALSE) {
-		return &ast.LiteralExpr{Object: false}, nil
+		return &ast.LiteralExpr{Node: p.nodeFrom(p.previous), Object: false}, nil
 	}
 	if p.match(token.TRUE) {
-		return &ast.LiteralExpr{Object: true}, nil
+		return &ast.LiteralExpr{Node: p.nodeFrom(p.previous), Object: true}, nil
 	}
 	if p.match(token.NIL) {
-		return &ast.LiteralExpr{Object: nil}, nil
+		return &ast.LiteralExpr{Node: p.nodeFrom(p.previous), Object: nil}, nil
 	}
 
 	if p.match(token.NUMBER, token.STRING) {
-		return &ast.LiteralExpr{Object: p.previous().Literal}, nil
+		return &ast.LiteralExpr{Node: p.nodeFrom(p.previous), Object: p.previous.Literal}, nil
 	}
 	if p.match(token.LEFTPAREN) {
+		paren := p.previous
 		expr, err := p.expression()
 		if err != nil {
 			return nil, err
 		}
-		p.consume(token.RIGHTPAREN, "Expect ')' after expression.")
-		return &ast.GroupExpr{Expression: expr}, nil
+		if _, err := p.consume(token.RIGHTPAREN, "Expect ')' after expression."); err != nil {
+			return nil, err
+		}
+		return &ast.GroupExpr{Node: p.nodeFrom(paren), Expression: expr}, nil
+	}
+	if p.match(token.SUPER) {
+		keyword := p.previous
+		if _, err := p.consume(token.DOT, "Expected '.' after 'super'."); err != nil {
+			return nil, err
+		}
+		method, err := p.consume(token.IDENTIFIER, "Expected superclass method name.")
+		if err != nil {
+			return nil, err
+		}
+		return &ast.SuperExpr{Node: p.nodeFrom(keyword), Keyword: keyword, Method: method}, nil
+	}
+	if p.match(token.THIS) {
+		return &ast.ThisExpr{Node: p.nodeFrom(p.previous), Keyword: p.previous}, nil
 	}
 	if p.match(token.IDENTIFIER) {
-		return &ast.VariableExpr{Name: p.previous()}, nil
+		return &ast.VariableExpr{Node: p.nodeFrom(p.previous), Name: p.previous}, nil
 	}
 	return nil, &parseerror.ParseError{Token: p.peek(), Message: "Expected an expression"}
 }
@@ -278,25 +778,19 @@ func (p *Parser) consume(typ token.Type, message string) (token.Token, error) {
 	if p.check(typ) {
 		return p.advance(), nil
 	}
-	return p.previous(), &parseerror.ParseError{Token: p.peek(), Message: message}
+	return p.previous, &parseerror.ParseError{Token: p.peek(), Message: message}
 }
 
 // synchronize discards token until it finds a statement
 func (p *Parser) synchronize() {
 	p.advance()
 	for !p.isAtEnd() {
-		if p.previous().Type == token.SEMICOLON {
+		if p.previous.Type == token.SEMICOLON {
 			return
 		}
 		switch p.peek().Type {
-		case token.CLASS:
-		case token.FUN:
-		case token.VAR:
-		case token.FOR:
-		case token.IF:
-		case token.WHILE:
-		case token.PRINT:
-		case token.RETURN:
+		case token.CLASS, token.FUN, token.VAR, token.FOR, token.IF, token.WHILE,
+			token.PRINT, token.RETURN, token.BREAK, token.CONTINUE:
 			return
 		}
 		p.advance()
@@ -304,21 +798,25 @@ func (p *Parser) synchronize() {
 }
 
 func (p *Parser) assignment() (ast.Expr, error) {
-	expr, err := p.equality()
+	expr, err := p.conditional()
 	if err != nil {
 		return nil, err
 	}
 	if p.match(token.EQUAL) {
-		equals := p.previous()
+		equals := p.previous
 		value, err := p.assignment()
 		if err != nil {
 			return nil, err
 		}
-		e, ok := expr.(*ast.VariableExpr)
-		if ok {
-			return &ast.AssignExpr{Name: e.Name, Value: value}, nil
+		switch e := expr.(type) {
+		case *ast.VariableExpr:
+			return &ast.AssignExpr{Node: p.nodeFromExpr(expr), Name: e.Name, Value: value}, nil
+		case *ast.GetExpr:
+			return &ast.SetExpr{Node: p.nodeFromExpr(expr), Object: e.Expression, Name: e.Name, Value: value}, nil
 		}
-		parseerror.ReportError(equals.Line, "Invalid assignment target.")
+		// the parser is not confused by the target so there is no need to
+		// synchronize
+		p.error(&parseerror.ParseError{Token: equals, Message: "Invalid assignment target."})
 	}
 	return expr, nil
 }
@@ -2,22 +2,363 @@ package parser
 
 import (
 	"fmt"
+	"lo/ast"
+	"lo/parseerror"
 	"lo/scanner"
+	"lo/token"
+	"strings"
 	"testing"
 )
 
+// newParser scans source and returns a Parser over its tokens
+func newParser(t *testing.T, source string) *Parser {
+	t.Helper()
+	sc := scanner.NewScanner(source, parseerror.NewDiagnostics())
+	tokens, err := sc.ScanTokens()
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	return NewParser(tokens, parseerror.NewDiagnostics())
+}
+
 func TestParser(t *testing.T) {
 	source := `1+2+9.22;`
-	sc := scanner.NewScanner(source)
-	tokens := sc.ScanTokens()
-	pa := NewParser(tokens)
-	expression, err := pa.Parse()
+	pa := newParser(t, source)
+	stmts, err := pa.Parse()
 	if err != nil {
-		t.Errorf("%s", err)
+		t.Fatalf("%s", err)
 	}
+	expression := stmts[0]
 	expected := "(+ (+ 1 2) 9.22)"
 
 	if fmt.Sprintf("%s", expression) != expected {
 		t.Errorf("expected %s but got %s", expected, expression)
 	}
 }
+
+func TestParseBlock(t *testing.T) {
+	source := `var a = 1; { var a = a + 1; print a; }`
+	pa := newParser(t, source)
+	stmts, err := pa.Parse()
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	expected := "[(var a 1) (block (var a (+ a 1)) (print a))]"
+	if fmt.Sprint(stmts) != expected {
+		t.Errorf("expected %s but got %s", expected, stmts)
+	}
+
+	pa = newParser(t, `{ print 1;`)
+	if _, err := pa.Parse(); err == nil {
+		t.Errorf("expected an error for an unterminated block")
+	}
+}
+
+func TestParseControlFlow(t *testing.T) {
+	testCases := []struct {
+		source   string
+		expected string
+	}{
+		{`if (a) print 1;`, "[(if a (print 1))]"},
+		{`if (a) print 1; else print 2;`, "[(if a (print 1) (print 2))]"},
+		{`while (a) a = false;`, "[(while a a false)]"},
+		{`for (;;) print 1;`, "[(while true (print 1))]"},
+		{`for (var i = 0; i < 2; i = i + 1) print i;`,
+			"[(block (var i 0) (while (< i 2) (print i) i (+ i 1)))]"},
+	}
+	for _, tt := range testCases {
+		pa := newParser(t, tt.source)
+		stmts, err := pa.Parse()
+		if err != nil {
+			t.Fatalf("%s: %s", tt.source, err)
+		}
+		if fmt.Sprint(stmts) != tt.expected {
+			t.Errorf("expected %s but got %s", tt.expected, stmts)
+		}
+	}
+}
+
+func TestParseLogical(t *testing.T) {
+	source := `a or b and c == d;`
+	pa := newParser(t, source)
+	stmts, err := pa.Parse()
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	expected := "[(or a (and b (== c d)))]"
+	if fmt.Sprint(stmts) != expected {
+		t.Errorf("expected %s but got %s", expected, stmts)
+	}
+}
+
+func TestParseFunctions(t *testing.T) {
+	source := `fun add(a, b) { return a + b; } add(1, 2)(3);`
+	pa := newParser(t, source)
+	stmts, err := pa.Parse()
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	expected := "[(fun add (a b) (return (+ a b))) (call (call add 1 2 ) 3 )]"
+	if fmt.Sprint(stmts) != expected {
+		t.Errorf("expected %s but got %s", expected, stmts)
+	}
+}
+
+func TestParseTooManyArguments(t *testing.T) {
+	source := "f(" + strings.Repeat("1, ", 255) + "1);"
+	pa := newParser(t, source)
+	_, err := pa.Parse()
+	if err == nil {
+		t.Fatalf("expected an error for more than 255 arguments")
+	}
+	if !strings.Contains(err.Error(), "Can't have more than 255 arguments.") {
+		t.Errorf("unexpected error %s", err)
+	}
+}
+
+func TestParseClass(t *testing.T) {
+	source := `class Point { init(x) { this.x = x; } } Point(1).x;`
+	pa := newParser(t, source)
+	stmts, err := pa.Parse()
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	expected := "[(class Point (fun init (x) (set this x x))) (. (call Point 1 ) x)]"
+	if fmt.Sprint(stmts) != expected {
+		t.Errorf("expected %s but got %s", expected, stmts)
+	}
+}
+
+func TestParseSubclass(t *testing.T) {
+	source := `class B < A { m() { return super.m(); } }`
+	pa := newParser(t, source)
+	stmts, err := pa.Parse()
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	expected := "[(class B < A (fun m () (return (call (super m) ))))]"
+	if fmt.Sprint(stmts) != expected {
+		t.Errorf("expected %s but got %s", expected, stmts)
+	}
+}
+
+func TestParseLoopControl(t *testing.T) {
+	source := `while (true) { if (a) break; continue; }`
+	pa := newParser(t, source)
+	stmts, err := pa.Parse()
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	expected := "[(while true (block (if a (break)) (continue)))]"
+	if fmt.Sprint(stmts) != expected {
+		t.Errorf("expected %s but got %s", expected, stmts)
+	}
+
+	invalid := []string{
+		`break;`,
+		`if (a) continue;`,
+		`while (true) { fun f() { break; } }`,
+	}
+	for _, source := range invalid {
+		pa := newParser(t, source)
+		if _, err := pa.Parse(); err == nil {
+			t.Errorf("%s: expected an error outside of a loop", source)
+		}
+	}
+}
+
+func TestParseConditional(t *testing.T) {
+	testCases := []struct {
+		source   string
+		expected string
+	}{
+		{`a ? b : c;`, "[(?: a b c)]"},
+		{`a ? b : c ? d : e;`, "[(?: a b (?: c d e))]"},
+		{`a or b ? c and d : e;`, "[(?: (or a b) (and c d) e)]"},
+		{`x = a ? b : c;`, "[x (?: a b c)]"},
+	}
+	for _, tt := range testCases {
+		pa := newParser(t, tt.source)
+		stmts, err := pa.Parse()
+		if err != nil {
+			t.Fatalf("%s: %s", tt.source, err)
+		}
+		if fmt.Sprint(stmts) != tt.expected {
+			t.Errorf("expected %s but got %s", tt.expected, stmts)
+		}
+	}
+}
+
+func TestParsePower(t *testing.T) {
+	testCases := []struct {
+		source   string
+		expected string
+	}{
+		{`2 ** 3 ** 2;`, "[(** 2 (** 3 2))]"},
+		{`-2 ** 2;`, "[-(** 2 2)]"},
+		{`2 ** -1;`, "[(** 2 -1)]"},
+		{`2 * 3 ** 2;`, "[(* 2 (** 3 2))]"},
+	}
+	for _, tt := range testCases {
+		pa := newParser(t, tt.source)
+		stmts, err := pa.Parse()
+		if err != nil {
+			t.Fatalf("%s: %s", tt.source, err)
+		}
+		if fmt.Sprint(stmts) != tt.expected {
+			t.Errorf("expected %s but got %s", tt.expected, stmts)
+		}
+	}
+}
+
+func TestParseReportsEveryError(t *testing.T) {
+	source := `var a = ;
+print a;
+var = 2;
+{
+	print (1;
+	print 2;
+}
+fun f( { }
+print 3;
+1 = 2;`
+	diagnostics := parseerror.NewDiagnostics()
+	sc := scanner.NewScanner(source, diagnostics)
+	tokens, err := sc.ScanTokens()
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	pa := NewParser(tokens, diagnostics)
+	stmts, err := pa.Parse()
+
+	errs, ok := err.(parseerror.ErrorList)
+	if !ok {
+		t.Fatalf("expected an ErrorList but got %v", err)
+	}
+	expectedErrors := []string{
+		"[line 1] Error at ';': Expected an expression",
+		"[line 3] Error at '=': Expected a variable name.",
+		"[line 5] Error at ';': Expect ')' after expression.",
+		"[line 8] Error at '{': Expected parameter name.",
+		"[line 10] Error at '=': Invalid assignment target.",
+	}
+	if len(errs) != len(expectedErrors) {
+		t.Fatalf("expected %d errors but got %d: %s", len(expectedErrors), len(errs), errs)
+	}
+	for i, expected := range expectedErrors {
+		if errs[i].Error() != expected {
+			t.Errorf("[error %d] expected %q but got %q", i, expected, errs[i])
+		}
+	}
+	if len(diagnostics.Errors()) != len(expectedErrors) {
+		t.Errorf("expected every error to be reported to the diagnostics")
+	}
+
+	expected := `[(print a) (block (print 2)) (print 3) 1]`
+	if fmt.Sprint(stmts) != expected {
+		t.Errorf("expected the partial AST %s but got %s", expected, stmts)
+	}
+}
+
+func TestParseSpans(t *testing.T) {
+	source := `var a = (1 + 2) * 3;
+if (a > 1) print -a;
+fun f(x) { return x.y(1); }`
+	stmts, err := newParser(t, source).Parse()
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	varStmt := stmts[0].(*ast.VarStmt)
+	ifStmt := stmts[1].(*ast.IfStmt)
+	funStmt := stmts[2].(*ast.FunctionStmt)
+	returnStmt := funStmt.Body[0].(*ast.ReturnStmt)
+
+	testCases := []struct {
+		node     interface{ Span() token.Span }
+		expected string
+	}{
+		{varStmt, "var a = (1 + 2) * 3;"},
+		{varStmt.Initializer, "(1 + 2) * 3"},
+		{varStmt.Initializer.(*ast.BinaryExpr).Left, "(1 + 2)"},
+		{ifStmt, "if (a > 1) print -a;"},
+		{ifStmt.Condition, "a > 1"},
+		{ifStmt.ThenBranch.(*ast.PrintStmt).Expression, "-a"},
+		{funStmt, "fun f(x) { return x.y(1); }"},
+		{returnStmt, "return x.y(1);"},
+		{returnStmt.Value, "x.y(1)"},
+		{returnStmt.Value.(*ast.CallExpr).Callee, "x.y"},
+	}
+	for _, tt := range testCases {
+		span := tt.node.Span()
+		if got := source[span.Start:span.End]; got != tt.expected {
+			t.Errorf("expected the span to cover %q but got %q", tt.expected, got)
+		}
+	}
+}
+
+func TestStreamParser(t *testing.T) {
+	source := `var a = 1; { var a = a + 1; print a; }`
+	sc := scanner.NewReaderScanner(strings.NewReader(source), parseerror.NewDiagnostics())
+	stmts, err := NewStreamParser(sc, parseerror.NewDiagnostics()).Parse()
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	expected := "[(var a 1) (block (var a (+ a 1)) (print a))]"
+	if fmt.Sprint(stmts) != expected {
+		t.Errorf("expected %s but got %s", expected, stmts)
+	}
+
+	diagnostics := parseerror.NewDiagnostics()
+	sc = scanner.NewReaderScanner(strings.NewReader("print 1 # 2;\nprint 3;"), diagnostics)
+	stmts, err = NewStreamParser(sc, diagnostics).Parse()
+	errs, ok := err.(parseerror.ErrorList)
+	if !ok || len(errs) != 2 {
+		t.Fatalf("expected the scanner and parser errors but got %v", err)
+	}
+	if _, ok := errs[0].(scanner.UnexpectedCharacterError); !ok {
+		t.Errorf("expected the scanner error first but got %v", errs[0])
+	}
+	if len(diagnostics.Errors()) != 2 {
+		t.Errorf("expected each error to be reported once but got %d", len(diagnostics.Errors()))
+	}
+	if fmt.Sprint(stmts) != "[(print 3)]" {
+		t.Errorf("expected the partial AST [(print 3)] but got %s", stmts)
+	}
+}
+
+func TestParseSkippedComments(t *testing.T) {
+	sc := scanner.NewScanner("/* header */ var a = 1; /* a /* nested */ note */ print a;", parseerror.NewDiagnostics())
+	sc.SkipComments = true
+	stmts, err := NewStreamParser(sc, parseerror.NewDiagnostics()).Parse()
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	expected := "[(var a 1) (print a)]"
+	if fmt.Sprint(stmts) != expected {
+		t.Errorf("expected %s but got %s", expected, stmts)
+	}
+}
+
+func TestParseAfterScanErrors(t *testing.T) {
+	diagnostics := parseerror.NewDiagnostics()
+	tokens, err := scanner.NewScanner("var = 1;\nprint (1 + ;\nprint 2;\n@", diagnostics).ScanTokens()
+	if err == nil {
+		t.Fatalf("expected a scanner error")
+	}
+	if _, err := NewParser(tokens, diagnostics).Parse(); err == nil {
+		t.Fatalf("expected the syntax errors to still be found")
+	}
+
+	errs := diagnostics.Errors()
+	if len(errs) != 3 {
+		t.Fatalf("expected the scanner and parser errors together but got %d: %v", len(errs), errs)
+	}
+	if _, ok := errs[0].(scanner.UnexpectedCharacterError); !ok {
+		t.Errorf("expected the scanner error first but got %v", errs[0])
+	}
+	for _, err := range errs[1:] {
+		if _, ok := err.(*parseerror.ParseError); !ok {
+			t.Errorf("expected a ParseError but got %v", err)
+		}
+	}
+}
@@ -0,0 +1,363 @@
+package resolver
+
+import (
+	"lo/ast"
+	"lo/parseerror"
+	"lo/token"
+)
+
+// functionType tells the resolver what kind of function body it is in
+type functionType int
+
+const (
+	none functionType = iota
+	function
+	method
+	initializer
+)
+
+// classType tells the resolver whether it is inside a class body
+type classType int
+
+const (
+	noClass classType = iota
+	class
+	subclass
+)
+
+// Resolver walks the statements once before they are interpreted and
+// tells the Interpreter how many scopes out each local variable lives
+type Resolver struct {
+	interpreter     *ast.Interpreter
+	scopes          []map[string]bool
+	currentFunction functionType
+	currentClass    classType
+	errors          parseerror.ErrorList
+	diagnostics     *parseerror.Diagnostics
+}
+
+// NewResolver creates a Resolver that annotates the given Interpreter and
+// reports errors to diagnostics
+func NewResolver(interpreter *ast.Interpreter, diagnostics *parseerror.Diagnostics) *Resolver {
+	return &Resolver{
+		interpreter:     interpreter,
+		scopes:          make([]map[string]bool, 0),
+		currentFunction: none,
+		currentClass:    noClass,
+		diagnostics:     diagnostics,
+	}
+}
+
+// Resolve resolves the statements and returns an ErrorList of every static
+// error found
+func (r *Resolver) Resolve(stmts []ast.Stmt) error {
+	r.resolveStmts(stmts)
+	if len(r.errors) > 0 {
+		return r.errors
+	}
+	return nil
+}
+
+// resolveStmts resolves a list of statements in order
+func (r *Resolver) resolveStmts(stmts []ast.Stmt) {
+	for _, stmt := range stmts {
+		r.resolveStmt(stmt)
+	}
+}
+
+// resolveStmt is a helper that revisits the resolver for statements
+func (r *Resolver) resolveStmt(stmt ast.Stmt) {
+	stmt.Accept(r)
+}
+
+// resolveExpr is a helper that revisits the resolver for expressions
+func (r *Resolver) resolveExpr(expr ast.Expr) {
+	expr.Accept(r)
+}
+
+// beginScope pushes a new innermost scope
+func (r *Resolver) beginScope() {
+	r.scopes = append(r.scopes, make(map[string]bool))
+}
+
+// endScope pops the innermost scope
+func (r *Resolver) endScope() {
+	r.scopes = r.scopes[:len(r.scopes)-1]
+}
+
+// declare adds the name to the innermost scope as not ready for use yet
+func (r *Resolver) declare(name token.Token) {
+	if len(r.scopes) == 0 {
+		return
+	}
+	scope := r.scopes[len(r.scopes)-1]
+	if _, found := scope[name.Lexeme]; found {
+		r.error(name, "Already a variable with this name in this scope.")
+	}
+	scope[name.Lexeme] = false
+}
+
+// define marks the name in the innermost scope as ready for use
+func (r *Resolver) define(name token.Token) {
+	if len(r.scopes) == 0 {
+		return
+	}
+	r.scopes[len(r.scopes)-1][name.Lexeme] = true
+}
+
+// resolveLocal tells the Interpreter the distance from the innermost scope
+// to the scope the name is declared in. Names not found are globals
+func (r *Resolver) resolveLocal(expr ast.Expr, name token.Token) {
+	for idx := len(r.scopes) - 1; idx >= 0; idx-- {
+		if _, found := r.scopes[idx][name.Lexeme]; found {
+			r.interpreter.Resolve(expr, len(r.scopes)-1-idx)
+			return
+		}
+	}
+}
+
+// resolveFunction resolves the parameters and body of a function in a
+// new scope
+func (r *Resolver) resolveFunction(stmt *ast.FunctionStmt, typ functionType) {
+	enclosingFunction := r.currentFunction
+	r.currentFunction = typ
+	r.beginScope()
+	for _, param := range stmt.Params {
+		r.declare(param)
+		r.define(param)
+	}
+	r.resolveStmts(stmt.Body)
+	r.endScope()
+	r.currentFunction = enclosingFunction
+}
+
+// error records and reports a static error at the given token
+func (r *Resolver) error(t token.Token, message string) {
+	err := &parseerror.ResolveError{Token: t, Message: message}
+	r.errors = append(r.errors, err)
+	r.diagnostics.Report(err)
+}
+
+// VisitBlockStmt resolves the block statements in a new scope
+func (r *Resolver) VisitBlockStmt(stmt *ast.BlockStmt) interface{} {
+	r.beginScope()
+	r.resolveStmts(stmt.Statements)
+	r.endScope()
+	return nil
+}
+
+// VisitClassStmt resolves the methods in a scope that binds this
+func (r *Resolver) VisitClassStmt(stmt *ast.ClassStmt) interface{} {
+	enclosingClass := r.currentClass
+	r.currentClass = class
+	r.declare(stmt.Name)
+	r.define(stmt.Name)
+
+	if stmt.Superclass != nil {
+		if stmt.Superclass.Name.Lexeme == stmt.Name.Lexeme {
+			r.error(stmt.Superclass.Name, "A class can't inherit from itself.")
+		}
+		r.currentClass = subclass
+		r.resolveExpr(stmt.Superclass)
+		r.beginScope()
+		r.scopes[len(r.scopes)-1]["super"] = true
+	}
+
+	r.beginScope()
+	r.scopes[len(r.scopes)-1]["this"] = true
+	for _, m := range stmt.Methods {
+		typ := method
+		if m.Name.Lexeme == "init" {
+			typ = initializer
+		}
+		r.resolveFunction(m, typ)
+	}
+	r.endScope()
+	if stmt.Superclass != nil {
+		r.endScope()
+	}
+	r.currentClass = enclosingClass
+	return nil
+}
+
+// VisitExpressionStmt ...
+func (r *Resolver) VisitExpressionStmt(stmt *ast.ExpressionStmt) interface{} {
+	r.resolveExpr(stmt.Expression)
+	return nil
+}
+
+// VisitFunctionStmt defines the function name before resolving the body so
+// that the function can call itself
+func (r *Resolver) VisitFunctionStmt(stmt *ast.FunctionStmt) interface{} {
+	r.declare(stmt.Name)
+	r.define(stmt.Name)
+	r.resolveFunction(stmt, function)
+	return nil
+}
+
+// VisitIfStmt resolves the condition and both branches
+func (r *Resolver) VisitIfStmt(stmt *ast.IfStmt) interface{} {
+	r.resolveExpr(stmt.Condition)
+	r.resolveStmt(stmt.ThenBranch)
+	if stmt.ElseBranch != nil {
+		r.resolveStmt(stmt.ElseBranch)
+	}
+	return nil
+}
+
+// VisitPrintStmt ...
+func (r *Resolver) VisitPrintStmt(stmt *ast.PrintStmt) interface{} {
+	r.resolveExpr(stmt.Expression)
+	return nil
+}
+
+// VisitReturnStmt reports a return outside of a function
+func (r *Resolver) VisitReturnStmt(stmt *ast.ReturnStmt) interface{} {
+	if r.currentFunction == none {
+		r.error(stmt.Keyword, "Can't return from top-level code.")
+	}
+	if stmt.Value != nil {
+		if r.currentFunction == initializer {
+			r.error(stmt.Keyword, "Can't return a value from an initializer.")
+		}
+		r.resolveExpr(stmt.Value)
+	}
+	return nil
+}
+
+// VisitVarStmt declares the variable before resolving the initializer so
+// that reading it in its own initializer can be reported
+func (r *Resolver) VisitVarStmt(stmt *ast.VarStmt) interface{} {
+	r.declare(stmt.Name)
+	if stmt.Initializer != nil {
+		r.resolveExpr(stmt.Initializer)
+	}
+	r.define(stmt.Name)
+	return nil
+}
+
+// VisitWhileStmt resolves the condition and the body once
+func (r *Resolver) VisitWhileStmt(stmt *ast.WhileStmt) interface{} {
+	r.resolveExpr(stmt.Condition)
+	r.resolveStmt(stmt.Body)
+	if stmt.Increment != nil {
+		r.resolveExpr(stmt.Increment)
+	}
+	return nil
+}
+
+// VisitBreakStmt ...
+func (r *Resolver) VisitBreakStmt(stmt *ast.BreakStmt) interface{} {
+	return nil
+}
+
+// VisitContinueStmt ...
+func (r *Resolver) VisitContinueStmt(stmt *ast.ContinueStmt) interface{} {
+	return nil
+}
+
+// VisitAssignExpression resolves the value then the variable assigned to
+func (r *Resolver) VisitAssignExpression(e *ast.AssignExpr) interface{} {
+	r.resolveExpr(e.Value)
+	r.resolveLocal(e, e.Name)
+	return nil
+}
+
+// VisitBinaryExpression ...
+func (r *Resolver) VisitBinaryExpression(e *ast.BinaryExpr) interface{} {
+	r.resolveExpr(e.Left)
+	r.resolveExpr(e.Right)
+	return nil
+}
+
+// VisitCallExpression ...
+func (r *Resolver) VisitCallExpression(e *ast.CallExpr) interface{} {
+	r.resolveExpr(e.Callee)
+	for _, argument := range e.Arguments {
+		r.resolveExpr(argument)
+	}
+	return nil
+}
+
+// VisitConditionalExpression ...
+func (r *Resolver) VisitConditionalExpression(e *ast.ConditionalExpr) interface{} {
+	r.resolveExpr(e.Condition)
+	r.resolveExpr(e.ThenBranch)
+	r.resolveExpr(e.ElseBranch)
+	return nil
+}
+
+// VisitGetExpression ...
+func (r *Resolver) VisitGetExpression(e *ast.GetExpr) interface{} {
+	r.resolveExpr(e.Expression)
+	return nil
+}
+
+// VisitGroupExpression ...
+func (r *Resolver) VisitGroupExpression(e *ast.GroupExpr) interface{} {
+	r.resolveExpr(e.Expression)
+	return nil
+}
+
+// VisitLiteralExpression ...
+func (r *Resolver) VisitLiteralExpression(e *ast.LiteralExpr) interface{} {
+	return nil
+}
+
+// VisitLogicalExpression ...
+func (r *Resolver) VisitLogicalExpression(e *ast.LogicalExpr) interface{} {
+	r.resolveExpr(e.Left)
+	r.resolveExpr(e.Right)
+	return nil
+}
+
+// VisitSetExpression ...
+func (r *Resolver) VisitSetExpression(e *ast.SetExpr) interface{} {
+	r.resolveExpr(e.Value)
+	r.resolveExpr(e.Object)
+	return nil
+}
+
+// VisitSuperExpression reports super outside of a subclass and resolves it
+// like a variable
+func (r *Resolver) VisitSuperExpression(e *ast.SuperExpr) interface{} {
+	if r.currentClass == noClass {
+		r.error(e.Keyword, "Can't use 'super' outside of a class.")
+		return nil
+	}
+	if r.currentClass != subclass {
+		r.error(e.Keyword, "Can't use 'super' in a class with no superclass.")
+		return nil
+	}
+	r.resolveLocal(e, e.Keyword)
+	return nil
+}
+
+// VisitThisExpression reports this outside of a class and resolves it like
+// a variable
+func (r *Resolver) VisitThisExpression(e *ast.ThisExpr) interface{} {
+	if r.currentClass == noClass {
+		r.error(e.Keyword, "Can't use 'this' outside of a class.")
+		return nil
+	}
+	r.resolveLocal(e, e.Keyword)
+	return nil
+}
+
+// VisitUnaryExpression ...
+func (r *Resolver) VisitUnaryExpression(e *ast.UnaryExpr) interface{} {
+	r.resolveExpr(e.Right)
+	return nil
+}
+
+// VisitVariableExpression reports a local read in its own initializer and
+// resolves the variable
+func (r *Resolver) VisitVariableExpression(e *ast.VariableExpr) interface{} {
+	if len(r.scopes) > 0 {
+		if defined, found := r.scopes[len(r.scopes)-1][e.Name.Lexeme]; found && !defined {
+			r.error(e.Name, "Can't read local variable in its own initializer.")
+		}
+	}
+	r.resolveLocal(e, e.Name)
+	return nil
+}
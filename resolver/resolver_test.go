@@ -0,0 +1,90 @@
+package resolver
+
+import (
+	"lo/ast"
+	"lo/parseerror"
+	"lo/parser"
+	"lo/scanner"
+	"lo/token"
+	"strings"
+	"testing"
+)
+
+// resolve parses source and resolves it against interpreter
+func resolve(t *testing.T, interpreter *ast.Interpreter, source string) ([]ast.Stmt, error) {
+	t.Helper()
+	sc := scanner.NewScanner(source, parseerror.NewDiagnostics())
+	tokens, err := sc.ScanTokens()
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	p := parser.NewParser(tokens, parseerror.NewDiagnostics())
+	stmts, err := p.Parse()
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	return stmts, NewResolver(interpreter, parseerror.NewDiagnostics()).Resolve(stmts)
+}
+
+func TestResolveClosureBinding(t *testing.T) {
+	interpreter := ast.NewInterpreter(parseerror.NewDiagnostics())
+	stmts, err := resolve(t, interpreter, `
+	var a = "global";
+	var first;
+	var second;
+	{
+		fun showA() { return a; }
+		first = showA();
+		var a = "block";
+		second = showA();
+	}
+	`)
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	if err := interpreter.Interpret(stmts); err != nil {
+		t.Fatalf("%s", err)
+	}
+
+	for _, name := range []string{"first", "second"} {
+		got, err := interpreter.Globals.Get(token.Token{Type: token.IDENTIFIER, Lexeme: name})
+		if err != nil {
+			t.Fatalf("%s", err)
+		}
+		if got != "global" {
+			t.Errorf("expected %s to be global but got %v", name, got)
+		}
+	}
+}
+
+func TestResolveErrors(t *testing.T) {
+	testCases := []struct {
+		source   string
+		expected string
+	}{
+		{`{ var a = 1; var a = 2; }`, "Already a variable with this name in this scope."},
+		{`fun f(a, a) {}`, "Already a variable with this name in this scope."},
+		{`var a = 1; { var a = a; }`, "Can't read local variable in its own initializer."},
+		{`return 1;`, "Can't return from top-level code."},
+		{`print this;`, "Can't use 'this' outside of a class."},
+		{`fun f() { return this; }`, "Can't use 'this' outside of a class."},
+		{`class A { init() { return 1; } }`, "Can't return a value from an initializer."},
+		{`class A < A {}`, "A class can't inherit from itself."},
+		{`super.m();`, "Can't use 'super' outside of a class."},
+		{`class A { m() { super.m(); } }`, "Can't use 'super' in a class with no superclass."},
+	}
+	for _, tt := range testCases {
+		_, err := resolve(t, ast.NewInterpreter(parseerror.NewDiagnostics()), tt.source)
+		if err == nil {
+			t.Errorf("%s: expected an error", tt.source)
+			continue
+		}
+		if !strings.Contains(err.Error(), tt.expected) {
+			t.Errorf("%s: expected %q but got %q", tt.source, tt.expected, err)
+		}
+	}
+
+	if _, err := resolve(t, ast.NewInterpreter(parseerror.NewDiagnostics()), `var a = 1; var a = a;`); err != nil {
+		t.Errorf("expected globals to be redeclarable but got %s", err)
+	}
+}
@@ -2,32 +2,137 @@ package scanner
 
 import (
 	"fmt"
+	"lo/parseerror"
+	"lo/token"
+)
+
+// Codes of the scanner errors in the machine-readable diagnostics, next to
+// those of the parseerror package
+const (
+	CodeUnexpectedCharacter = "E0001"
+	CodeUnterminatedString  = "E0002"
+	CodeUnterminatedComment = "E0003"
+	CodeInvalidEscape       = "E0004"
+	CodeMalformedNumber     = "E0005"
 )
 
 // UnexpectedCharacterError gives the error of an unexpected character in source
 type UnexpectedCharacterError struct {
-	line      int
-	character string
+	Line      int
+	Column    int
+	Span      token.Span
+	Character string
 }
 
 func (e UnexpectedCharacterError) Error() string {
-	return fmt.Sprintf("Unexpected character %s on line %d\n", e.character, e.line)
+	return fmt.Sprintf("[line %d, column %d] Error: Unexpected character '%s'.", e.Line, e.Column, e.Character)
 }
 
-// UnterminatedStringError raised when a string is not closed with a double quote
+// Diagnostic places the UnexpectedCharacterError on the character
+func (e UnexpectedCharacterError) Diagnostic() parseerror.Diagnostic {
+	return parseerror.Diagnostic{
+		Code:    CodeUnexpectedCharacter,
+		Message: fmt.Sprintf("Unexpected character '%s'.", e.Character),
+		Line:    e.Line,
+		Column:  e.Column,
+		Span:    e.Span,
+	}
+}
+
+// UnterminatedStringError raised when a string is not closed with a double
+// quote. The position is that of the opening quote
 type UnterminatedStringError struct {
-	line int
+	Line   int
+	Column int
+	Span   token.Span
 }
 
 func (e UnterminatedStringError) Error() string {
-	return fmt.Sprintf("Unterminated string on col %d\n", e.line)
+	return fmt.Sprintf("[line %d, column %d] Error: Unterminated string.", e.Line, e.Column)
+}
+
+// Diagnostic places the UnterminatedStringError on the unclosed string
+func (e UnterminatedStringError) Diagnostic() parseerror.Diagnostic {
+	return parseerror.Diagnostic{
+		Code:    CodeUnterminatedString,
+		Message: "Unterminated string.",
+		Line:    e.Line,
+		Column:  e.Column,
+		Span:    e.Span,
+		Help:    "add a closing '\"' to end the string",
+	}
 }
 
-// UnterminatedCommentError raised when a string is not closed with a double quote
+// UnterminatedCommentError raised when a block comment is not closed with */.
+// The position is that of the opening /*
 type UnterminatedCommentError struct {
-	line int
+	Line   int
+	Column int
+	Span   token.Span
 }
 
 func (e UnterminatedCommentError) Error() string {
-	return fmt.Sprintf("Unterminated comment on col %d\n", e.line)
+	return fmt.Sprintf("[line %d, column %d] Error: Unterminated comment.", e.Line, e.Column)
+}
+
+// Diagnostic places the UnterminatedCommentError on the unclosed comment
+func (e UnterminatedCommentError) Diagnostic() parseerror.Diagnostic {
+	return parseerror.Diagnostic{
+		Code:    CodeUnterminatedComment,
+		Message: "Unterminated comment.",
+		Line:    e.Line,
+		Column:  e.Column,
+		Span:    e.Span,
+		Help:    "add a closing '*/' to end the comment",
+	}
+}
+
+// InvalidEscapeError raised when a backslash in a string is not followed by
+// a known escape sequence. The position is that of the backslash
+type InvalidEscapeError struct {
+	Line     int
+	Column   int
+	Span     token.Span
+	Sequence string
+}
+
+func (e InvalidEscapeError) Error() string {
+	return fmt.Sprintf("[line %d, column %d] Error: Invalid escape sequence '%s'.", e.Line, e.Column, e.Sequence)
+}
+
+// Diagnostic places the InvalidEscapeError on the escape sequence
+func (e InvalidEscapeError) Diagnostic() parseerror.Diagnostic {
+	return parseerror.Diagnostic{
+		Code:    CodeInvalidEscape,
+		Message: fmt.Sprintf("Invalid escape sequence '%s'.", e.Sequence),
+		Line:    e.Line,
+		Column:  e.Column,
+		Span:    e.Span,
+		Help:    `the escapes are \n \t \r \\ \" \0, \xHH up to \x7F and \u{HHHH}`,
+	}
+}
+
+// MalformedNumberError raised when a number literal can't be read, such as
+// 0x without digits or 1e without an exponent. Reason says what is wrong
+type MalformedNumberError struct {
+	Line   int
+	Column int
+	Span   token.Span
+	Number string
+	Reason string
+}
+
+func (e MalformedNumberError) Error() string {
+	return fmt.Sprintf("[line %d, column %d] Error: Malformed number '%s': %s.", e.Line, e.Column, e.Number, e.Reason)
+}
+
+// Diagnostic places the MalformedNumberError on the number
+func (e MalformedNumberError) Diagnostic() parseerror.Diagnostic {
+	return parseerror.Diagnostic{
+		Code:    CodeMalformedNumber,
+		Message: fmt.Sprintf("Malformed number '%s': %s.", e.Number, e.Reason),
+		Line:    e.Line,
+		Column:  e.Column,
+		Span:    e.Span,
+	}
 }
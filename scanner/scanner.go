@@ -1,50 +1,198 @@
 package scanner
 
 import (
+	"fmt"
+	"io"
 	"lo/parseerror"
 	"lo/token"
 	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
 )
 
 var keyWords = map[string]token.Type{
-	"and":    token.AND,
-	"class":  token.CLASS,
-	"else":   token.ELSE,
-	"false":  token.FALSE,
-	"for":    token.FOR,
-	"fun":    token.FUN,
-	"if":     token.IF,
-	"nil":    token.NIL,
-	"or":     token.OR,
-	"print":  token.PRINT,
-	"return": token.RETURN,
-	"super":  token.SUPER,
-	"this":   token.THIS,
-	"true":   token.TRUE,
-	"var":    token.VAR,
-	"while":  token.WHILE,
+	"and":      token.AND,
+	"break":    token.BREAK,
+	"class":    token.CLASS,
+	"continue": token.CONTINUE,
+	"else":     token.ELSE,
+	"false":    token.FALSE,
+	"for":      token.FOR,
+	"fun":      token.FUN,
+	"if":       token.IF,
+	"nil":      token.NIL,
+	"or":       token.OR,
+	"print":    token.PRINT,
+	"return":   token.RETURN,
+	"super":    token.SUPER,
+	"this":     token.THIS,
+	"true":     token.TRUE,
+	"var":      token.VAR,
+	"while":    token.WHILE,
 }
 
+// chunkSize is how much of the input a reader Scanner reads at a time
+const chunkSize = 4096
+
 // Scanner ...
 type Scanner struct {
 	start, current, line int
-	source               string
-	tokens               []token.Token
+	// column is the column of the current character, counted in runes
+	column int
+	// startLine and startColumn are where the current token starts
+	startLine, startColumn int
+	// source is a window on the input starting at offset base. The text of
+	// the tokens handed out is dropped and a reader Scanner reads more as
+	// it goes, so start and current are relative to the window
+	source      string
+	base        int
+	reader      io.Reader
+	chunk       []byte
+	tokens      []token.Token
+	errors      parseerror.ErrorList
+	returned    int
+	diagnostics *parseerror.Diagnostics
+	// SkipComments drops the COMMENT tokens instead of handing them out,
+	// as the parser has no use for them
+	SkipComments bool
+	// KeepTrivia attaches the whitespace, comments and bad input between
+	// tokens to the token after them as LeadingTrivia, so the full text of
+	// the tokens reproduces the source byte for byte. Block comments are
+	// then trivia rather than COMMENT tokens
+	KeepTrivia bool
+	trivia     []token.Trivia
+}
+
+// NewScanner creates a new Scanner that reports errors to diagnostics
+func NewScanner(source string, diagnostics *parseerror.Diagnostics) *Scanner {
+	return &Scanner{line: 1, column: 1, source: source, tokens: make([]token.Token, 0), diagnostics: diagnostics}
 }
 
-// NewScanner creates a new Scanner
-func NewScanner(source string) *Scanner {
-	return &Scanner{line: 1, source: source, tokens: make([]token.Token, 0)}
+// NewReaderScanner creates a Scanner that reads its source from reader as
+// the tokens are pulled with Next, so the whole input is never held in
+// memory. Errors are reported to diagnostics
+func NewReaderScanner(reader io.Reader, diagnostics *parseerror.Diagnostics) *Scanner {
+	return &Scanner{
+		line:        1,
+		column:      1,
+		reader:      reader,
+		chunk:       make([]byte, chunkSize),
+		tokens:      make([]token.Token, 0),
+		diagnostics: diagnostics,
+	}
 }
 
-// ScanTokens consumes the tokens in a source and returns them set to their types
-func (s *Scanner) ScanTokens() []token.Token {
-	for !s.isAtEnd() {
-		s.start = s.current
+// ScanTokens consumes the tokens in a source and returns them set to their
+// types. Scanning carries on past bad characters so the returned error is
+// an ErrorList of every scanner error in the source
+func (s *Scanner) ScanTokens() ([]token.Token, error) {
+	tokens := make([]token.Token, 0)
+	for {
+		tok, err := s.Next()
+		if err != nil {
+			// the error is kept in s.errors
+			continue
+		}
+		tokens = append(tokens, tok)
+		if tok.Type == token.EOF {
+			break
+		}
+	}
+	if len(s.errors) > 0 {
+		return tokens, s.errors
+	}
+	return tokens, nil
+}
+
+// Next scans and returns the next token. A scanner error is returned on
+// its own, after which Next can be called again to carry on scanning.
+// At the end of the source Next keeps returning an EOF token
+func (s *Scanner) Next() (token.Token, error) {
+	for len(s.tokens) == 0 && s.returned == len(s.errors) {
+		if s.isAtEnd() {
+			s.tokens = append(s.tokens, token.Token{
+				Type:          token.EOF,
+				Line:          s.line,
+				Column:        s.column,
+				Offset:        s.base + s.current,
+				EndOffset:     s.base + s.current,
+				LeadingTrivia: s.trivia,
+			})
+			s.trivia = nil
+			break
+		}
+		// the text before the new token is no longer needed
+		s.base += s.current
+		s.source = s.source[s.current:]
+		s.start, s.current = 0, 0
+		s.startLine = s.line
+		s.startColumn = s.column
+		scanned := len(s.tokens)
 		s.scanToken()
+		if s.KeepTrivia {
+			s.collectTrivia(scanned)
+		}
+	}
+	if s.returned < len(s.errors) {
+		s.returned++
+		return token.Token{}, s.errors[s.returned-1]
 	}
-	s.tokens = append(s.tokens, token.Token{Type: token.EOF})
-	return s.tokens
+	tok := s.tokens[0]
+	s.tokens = s.tokens[1:]
+	if tok.Type == token.COMMENT && s.SkipComments {
+		return s.Next()
+	}
+	return tok, nil
+}
+
+// collectTrivia turns what was just scanned into trivia unless it made a
+// token other than a comment, in which case the trivia gathered so far
+// is attached to the token. scanned is the number of tokens before
+func (s *Scanner) collectTrivia(scanned int) {
+	text := s.source[s.start:s.current]
+	var kind token.TriviaKind
+	switch {
+	case len(s.tokens) > scanned && s.tokens[scanned].Type == token.COMMENT:
+		s.tokens = s.tokens[:scanned]
+		kind = token.BLOCKCOMMENT
+	case len(s.tokens) > scanned:
+		s.tokens[scanned].LeadingTrivia = s.trivia
+		s.trivia = nil
+		return
+	case text == " " || text == "\t" || text == "\r":
+		kind = token.WHITESPACE
+	case text == "\n":
+		kind = token.NEWLINE
+	case strings.HasPrefix(text, "//"):
+		kind = token.LINECOMMENT
+	default:
+		kind = token.SKIPPED
+	}
+	// runs of spaces and tabs make a single piece of trivia
+	if last := len(s.trivia) - 1; kind == token.WHITESPACE && last >= 0 && s.trivia[last].Kind == token.WHITESPACE {
+		s.trivia[last].Text += text
+		return
+	}
+	s.trivia = append(s.trivia, token.Trivia{Kind: kind, Text: text})
+}
+
+// error records and reports a scanner error
+func (s *Scanner) error(err error) {
+	s.errors = append(s.errors, err)
+	s.diagnostics.Report(err)
+}
+
+// span is the range of source scanned so far for the current token
+func (s *Scanner) span() token.Span {
+	return token.Span{Start: s.base + s.start, End: s.base + s.current}
+}
+
+// newline moves the position on to the line starting at the current
+// character. It is called right after a "\n" is consumed
+func (s *Scanner) newline() {
+	s.line++
+	s.column = 1
 }
 
 // scanToken determines the type of Token and adds it to the Scanner
@@ -70,13 +218,15 @@ func (s *Scanner) scanToken() {
 		s.addToken(token.PLUS)
 	case ";":
 		s.addToken(token.SEMICOLON)
-	case "*":
-		if s.peekBack() != "/" || s.peek() != "/" {
-			s.addToken(token.STAR)
+	case "?":
+		s.addToken(token.QMARK)
+	case ":":
+		s.addToken(token.COLON)
+	case "*": // **
+		if s.match("*") {
+			s.addToken(token.POWER)
 		} else {
-			for s.peek() != "\n" && !s.isAtEnd() {
-				s.advance()
-			}
+			s.addToken(token.STAR)
 		}
 	case "!": // !=
 		if !s.match("=") {
@@ -118,7 +268,7 @@ func (s *Scanner) scanToken() {
 	case "\t":
 		break
 	case "\n":
-		s.line++
+		s.newline()
 	case "\"":
 		s.parseString()
 	default:
@@ -127,26 +277,35 @@ func (s *Scanner) scanToken() {
 		} else if s.isAlpha(sourceChar) {
 			s.identifier()
 		} else {
-			parseerror.LogError(UnexpectedCharacterError{line: s.line, character: sourceChar})
+			s.error(UnexpectedCharacterError{Line: s.startLine, Column: s.startColumn, Span: s.span(), Character: sourceChar})
 		}
 	}
 }
 
-// parseComment sets the comment tokens
+// parseComment consumes a block comment after its opening /*. Block
+// comments nest, so the comment ends at the */ closing the first /*
 func (s *Scanner) parseComment() {
-	for s.peek() != "*" && s.peekNext() != "/" && !s.isAtEnd() {
-		s.line++
-		s.advance()
+	depth := 1
+	for depth > 0 && !s.isAtEnd() {
+		char := s.advance()
+		switch {
+		case char == "/" && s.peek() == "*":
+			s.advance()
+			depth++
+		case char == "*" && s.peek() == "/":
+			s.advance()
+			depth--
+		case char == "\n":
+			s.newline()
+		}
 	}
-	if s.isAtEnd() {
-		parseerror.LogError(UnterminatedCommentError{line: s.line})
+	if depth > 0 {
+		s.error(UnterminatedCommentError{Line: s.startLine, Column: s.startColumn, Span: s.span()})
 		return
 	}
-	s.advance()
-	s.advance()
-	// string the double quotes on both ends of the string
+	// strip the /* and */ on both ends of the comment
 	commentString := s.source[s.start+2 : s.current-2]
-	s.addTokenWithLiteral(token.COMMENT, string(commentString))
+	s.addTokenWithLiteral(token.COMMENT, commentString)
 }
 
 // identifier sets an identifier
@@ -164,9 +323,10 @@ func (s *Scanner) identifier() {
 	s.addToken(tokenType)
 }
 
-// isAlpha checks whether a character is an alphabet or _
+// isAlpha checks whether a character is a Unicode letter or _
 func (s *Scanner) isAlpha(char string) bool {
-	return char >= "a" && char <= "z" || char >= "A" && char <= "Z" || char == "_"
+	r, _ := utf8.DecodeRuneInString(char)
+	return unicode.IsLetter(r) || char == "_"
 }
 
 // isAlphaNumeric checks whether a character is an alphabet, _ or number
@@ -179,62 +339,226 @@ func (s *Scanner) isDigit(char string) bool {
 	return char >= "0" && char <= "9"
 }
 
-// number consumes a number literal
+// radixes are the integer literals written in other bases than 10, by the
+// letter after their leading 0
+var radixes = map[string]struct {
+	base int
+	name string
+}{
+	"x": {16, "hexadecimal"},
+	"b": {2, "binary"},
+	"o": {8, "octal"},
+}
+
+// number consumes a number literal: a decimal with an optional fraction
+// and exponent such as 1.5e-9, or an integer in hexadecimal (0xFF), binary
+// (0b1010) or octal (0o17). Digits may be separated by underscores as in
+// 1_000_000. A malformed number is reported and scanned as 0
 func (s *Scanner) number() {
-	for s.isDigit(s.peek()) {
-		s.advance()
+	value, reason := s.numberValue()
+	// letters and digits right after a number are part of the mistake,
+	// as in 0b102 or 12abc
+	if s.isAlphaNumeric(s.peek()) {
+		char := s.peek()
+		for s.isAlphaNumeric(s.peek()) {
+			s.advance()
+		}
+		if reason == "" {
+			reason = fmt.Sprintf("unexpected '%s'", char)
+		}
 	}
+	if reason != "" {
+		s.error(MalformedNumberError{
+			Line:   s.startLine,
+			Column: s.startColumn,
+			Span:   s.span(),
+			Number: s.source[s.start:s.current],
+			Reason: reason,
+		})
+		value = 0
+	}
+	s.addTokenWithLiteral(token.NUMBER, value)
+}
 
+// numberValue scans the rest of a number whose first digit was consumed
+// and returns its value or the reason it is malformed
+func (s *Scanner) numberValue() (float64, string) {
+	if radix, ok := radixes[strings.ToLower(s.peek())]; ok && s.source[s.start:s.current] == "0" {
+		prefix := s.advance()
+		digits := s.digits(radix.base)
+		if digits == "" {
+			return 0, fmt.Sprintf("expected %s digits after '0%s'", radix.name, prefix)
+		}
+		if !validSeparators(digits) {
+			return 0, "'_' must be between digits"
+		}
+		value, err := strconv.ParseUint(strings.ReplaceAll(digits, "_", ""), radix.base, 64)
+		if err != nil {
+			return 0, "the number is too large"
+		}
+		return float64(value), ""
+	}
+
+	s.digits(10)
+	groups := []string{s.source[s.start:s.current]}
 	// Check if the number is followed by a decimal and a number
 	if s.peek() == "." && s.isDigit(s.peekNext()) {
 		s.advance()
-		for s.isDigit(s.peek()) {
+		groups = append(groups, s.digits(10))
+	}
+	if s.peek() == "e" || s.peek() == "E" {
+		s.advance()
+		if s.peek() == "+" || s.peek() == "-" {
 			s.advance()
 		}
+		exponent := s.digits(10)
+		if exponent == "" {
+			return 0, "expected digits in the exponent"
+		}
+		groups = append(groups, exponent)
+	}
+	for _, group := range groups {
+		if !validSeparators(group) {
+			return 0, "'_' must be between digits"
+		}
+	}
+	value, err := strconv.ParseFloat(strings.ReplaceAll(s.source[s.start:s.current], "_", ""), 64)
+	if err != nil {
+		return 0, "the number is too large"
 	}
-	// Maybe handle this error?
-	numberValue, _ := strconv.ParseFloat(s.source[s.start:s.current], 64)
-	s.addTokenWithLiteral(token.NUMBER, numberValue)
+	return value, ""
+}
+
+// digits consumes a run of digits in base along with the underscores
+// separating them and returns it
+func (s *Scanner) digits(base int) string {
+	begin := s.current
+	for s.isDigitIn(s.peek(), base) || s.peek() == "_" {
+		s.advance()
+	}
+	return s.source[begin:s.current]
+}
+
+// isDigitIn determines whether the character is a digit in base
+func (s *Scanner) isDigitIn(char string, base int) bool {
+	_, err := strconv.ParseUint(char, base, 8)
+	return len(char) == 1 && err == nil
 }
 
-// parseString consumes a string from the opening to the closing double quote
+// validSeparators checks that every underscore in a run of digits sits
+// between two digits
+func validSeparators(digits string) bool {
+	return !strings.HasPrefix(digits, "_") && !strings.HasSuffix(digits, "_") && !strings.Contains(digits, "__")
+}
+
+// parseString consumes a string from the opening to the closing double
+// quote. The literal is the string with its escape sequences decoded
 func (s *Scanner) parseString() {
+	var value strings.Builder
 	for s.peek() != "\"" && !s.isAtEnd() {
-		s.line++
-		s.advance()
+		char := s.advance()
+		switch char {
+		case "\n":
+			s.newline()
+			value.WriteString(char)
+		case "\\":
+			s.escape(&value)
+		default:
+			value.WriteString(char)
+		}
 	}
 	if s.isAtEnd() {
-		parseerror.LogError(UnterminatedStringError{line: s.line})
+		s.error(UnterminatedStringError{Line: s.startLine, Column: s.startColumn, Span: s.span()})
 		return
 	}
 	s.advance()
-	// string the double quotes on both ends of the string
-	stringValue := s.source[s.start+1 : s.current-1]
-	s.addTokenWithLiteral(token.STRING, string(stringValue))
+	s.addTokenWithLiteral(token.STRING, value.String())
 }
 
-// peek looks ahead one character without consuming any character
-func (s *Scanner) peek() string {
-	if s.isAtEnd() {
-		return "EOF"
+// escapes are the single character escape sequences and what they stand for
+var escapes = map[string]string{
+	"n":  "\n",
+	"t":  "\t",
+	"r":  "\r",
+	"0":  "\x00",
+	"\\": "\\",
+	"\"": "\"",
+}
+
+// escape decodes the escape sequence following a backslash in a string
+// into value. \xHH takes two hex digits up to 7F and \u{HHHH} up to six
+// hex digits naming a Unicode code point
+func (s *Scanner) escape(value *strings.Builder) {
+	begin := s.current - 1
+	column := s.column - 1
+	char := s.peek()
+	if decoded, ok := escapes[char]; ok {
+		s.advance()
+		value.WriteString(decoded)
+		return
 	}
-	return s.currentCharacter()
+
+	switch char {
+	case "x":
+		s.advance()
+		digits := s.hexDigits(2)
+		if code, err := strconv.ParseUint(digits, 16, 8); len(digits) == 2 && err == nil && code <= 0x7F {
+			value.WriteRune(rune(code))
+			return
+		}
+	case "u":
+		s.advance()
+		if s.match("{") {
+			digits := s.hexDigits(6)
+			closed := s.match("}")
+			if code, err := strconv.ParseUint(digits, 16, 32); closed && err == nil && utf8.ValidRune(rune(code)) {
+				value.WriteRune(rune(code))
+				return
+			}
+		}
+	default:
+		// take the whole character after the backslash into the error but
+		// leave a line break to the string
+		if !s.isAtEnd() && char != "\n" {
+			s.advance()
+		}
+	}
+	s.error(InvalidEscapeError{
+		Line:     s.line,
+		Column:   column,
+		Span:     token.Span{Start: s.base + begin, End: s.base + s.current},
+		Sequence: s.source[begin:s.current],
+	})
+}
+
+// hexDigits consumes up to max hexadecimal digits and returns them
+func (s *Scanner) hexDigits(max int) string {
+	begin := s.current
+	for s.current-begin < max && s.isDigitIn(s.peek(), 16) {
+		s.advance()
+	}
+	return s.source[begin:s.current]
 }
 
-// peekBack looks back one character without consuming any character
-func (s *Scanner) peekBack() string {
+// peek looks ahead one character without consuming any character. At the
+// end of the source it returns "\x00" which matches no character class
+func (s *Scanner) peek() string {
 	if s.isAtEnd() {
-		return "SOF"
+		return "\x00"
 	}
-	return string(s.source[s.current-1])
+	return s.currentCharacter()
 }
 
 // peekNext looks ahead at the character after peek()
 func (s *Scanner) peekNext() string {
-	if s.current+1 >= len(s.source) {
-		return "EOF"
+	if s.isAtEnd() {
+		return "\x00"
 	}
-	return string(s.source[s.current+1])
+	next := s.current + len(s.currentCharacter())
+	if !s.fill(next) {
+		return "\x00"
+	}
+	return s.characterAt(next)
 }
 
 // match checks the next character in the source and determines if it is a
@@ -246,20 +570,30 @@ func (s *Scanner) match(character string) bool {
 	if s.currentCharacter() != character {
 		return false
 	}
-	s.current++
+	s.advance()
 	return true
 }
 
 // currentCharacter gets and returns the current character in the source
 func (s *Scanner) currentCharacter() string {
-	return string(s.source[s.current])
+	return s.characterAt(s.current)
+}
+
+// characterAt decodes the rune starting at offset and returns it as a
+// string. Bytes that aren't valid UTF-8 are returned one at a time
+func (s *Scanner) characterAt(offset int) string {
+	s.fill(offset)
+	_, size := utf8.DecodeRuneInString(s.source[offset:])
+	return s.source[offset : offset+size]
 }
 
-// advance increases the value of the current position in the source. It then
-// determines the character at the current position and returns it
+// advance moves the current position past the next character in the
+// source and returns that character
 func (s *Scanner) advance() string {
-	s.current++
-	return string(s.source[s.current-1])
+	char := s.currentCharacter()
+	s.current += len(char)
+	s.column++
+	return char
 }
 
 // addToken appends a new token to the scanner
@@ -270,10 +604,36 @@ func (s *Scanner) addToken(token token.Type) {
 // addTokenWithLiteral sets a token with a Literal value i.e. string tokens
 func (s *Scanner) addTokenWithLiteral(tokenType token.Type, literal interface{}) {
 	lexeme := string(s.source[s.start:s.current])
-	s.tokens = append(s.tokens, token.Token{Type: tokenType, Lexeme: lexeme, Line: s.line, Literal: literal})
+	s.tokens = append(s.tokens, token.Token{
+		Type:      tokenType,
+		Lexeme:    lexeme,
+		Literal:   literal,
+		Line:      s.startLine,
+		Column:    s.startColumn,
+		Offset:    s.base + s.start,
+		EndOffset: s.base + s.current,
+	})
 }
 
 // isAtEnd signals consumption of all the characters in a source
 func (s *Scanner) isAtEnd() bool {
-	return s.current >= len(s.source)
+	return !s.fill(s.current)
+}
+
+// fill makes sure the window holds the whole character at offset, reading
+// more of the input when there is a reader. It reports whether there is a
+// character at offset at all. Reading stops at the first error, which is
+// reported unless it is io.EOF
+func (s *Scanner) fill(offset int) bool {
+	for s.reader != nil && len(s.source) < offset+utf8.UTFMax {
+		n, err := s.reader.Read(s.chunk)
+		s.source += string(s.chunk[:n])
+		if err != nil {
+			if err != io.EOF {
+				s.error(&parseerror.IOError{Err: err})
+			}
+			s.reader = nil
+		}
+	}
+	return offset < len(s.source)
 }
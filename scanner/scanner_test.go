@@ -1,8 +1,14 @@
 package scanner
 
 import (
+	"io/ioutil"
+	"lo/parseerror"
 	"lo/token"
+	"path/filepath"
+	"reflect"
+	"strings"
 	"testing"
+	"testing/iotest"
 )
 
 func TestScanTokens(t *testing.T) {
@@ -10,8 +16,11 @@ func TestScanTokens(t *testing.T) {
 	var num = 1+2+9.22
 	`
 
-	scanner := NewScanner(source)
-	tokens := scanner.ScanTokens()
+	scanner := NewScanner(source, parseerror.NewDiagnostics())
+	tokens, err := scanner.ScanTokens()
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
 
 	testCases := []struct {
 		expectedType   token.Type
@@ -36,3 +45,484 @@ func TestScanTokens(t *testing.T) {
 		}
 	}
 }
+
+func TestScanLoopKeywords(t *testing.T) {
+	tokens, err := NewScanner(`break continue breaks`, parseerror.NewDiagnostics()).ScanTokens()
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	expected := []token.Type{token.BREAK, token.CONTINUE, token.IDENTIFIER, token.EOF}
+	if len(tokens) != len(expected) {
+		t.Fatalf("expected %d tokens but got %d", len(expected), len(tokens))
+	}
+	for i, typ := range expected {
+		if tokens[i].Type != typ {
+			t.Errorf("[test %d] - wrong token Type. Expected %q, got %q", i, typ, tokens[i].Type)
+		}
+	}
+}
+
+func TestScanPower(t *testing.T) {
+	tokens, err := NewScanner(`2 ** 3 * 4`, parseerror.NewDiagnostics()).ScanTokens()
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	expected := []token.Type{token.NUMBER, token.POWER, token.NUMBER, token.STAR, token.NUMBER, token.EOF}
+	if len(tokens) != len(expected) {
+		t.Fatalf("expected %d tokens but got %d", len(expected), len(tokens))
+	}
+	for i, typ := range expected {
+		if tokens[i].Type != typ {
+			t.Errorf("[test %d] - wrong token Type. Expected %q, got %q", i, typ, tokens[i].Type)
+		}
+	}
+}
+
+func TestScanReportsErrors(t *testing.T) {
+	source := "var a = 1 @ 2;\nvar b = #;\nvar c = \"open"
+	diagnostics := parseerror.NewDiagnostics()
+	tokens, err := NewScanner(source, diagnostics).ScanTokens()
+
+	errs, ok := err.(parseerror.ErrorList)
+	if !ok {
+		t.Fatalf("expected an ErrorList but got %v", err)
+	}
+	expected := []error{
+		UnexpectedCharacterError{Line: 1, Column: 11, Span: token.Span{Start: 10, End: 11}, Character: "@"},
+		UnexpectedCharacterError{Line: 2, Column: 9, Span: token.Span{Start: 23, End: 24}, Character: "#"},
+		UnterminatedStringError{Line: 3, Column: 9, Span: token.Span{Start: 34, End: 39}},
+	}
+	if len(errs) != len(expected) {
+		t.Fatalf("expected %d errors but got %d: %s", len(expected), len(errs), errs)
+	}
+	for i := range expected {
+		if errs[i] != expected[i] {
+			t.Errorf("[error %d] expected %#v but got %#v", i, expected[i], errs[i])
+		}
+	}
+	codes := []string{CodeUnexpectedCharacter, CodeUnexpectedCharacter, CodeUnterminatedString}
+	for i, code := range codes {
+		if got := errs[i].(parseerror.Diagnoser).Diagnostic().Code; got != code {
+			t.Errorf("[error %d] expected code %s but got %s", i, code, got)
+		}
+	}
+	if !diagnostics.HadError() || len(diagnostics.Errors()) != len(expected) {
+		t.Errorf("expected every error to be reported to the diagnostics")
+	}
+	if tokens[len(tokens)-1].Type != token.EOF {
+		t.Errorf("expected the tokens to still end with EOF")
+	}
+}
+
+func TestScanPositions(t *testing.T) {
+	source := "var s = \"two\nlines\";\n/* a\ncomment */ print s;"
+	tokens, err := NewScanner(source, parseerror.NewDiagnostics()).ScanTokens()
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+
+	testCases := []struct {
+		expectedType   token.Type
+		expectedLine   int
+		expectedColumn int
+		expectedOffset int
+		expectedEnd    int
+	}{
+		{token.VAR, 1, 1, 0, 3},
+		{token.IDENTIFIER, 1, 5, 4, 5},
+		{token.EQUAL, 1, 7, 6, 7},
+		{token.STRING, 1, 9, 8, 19},
+		{token.SEMICOLON, 2, 7, 19, 20},
+		{token.COMMENT, 3, 1, 21, 36},
+		{token.PRINT, 4, 12, 37, 42},
+		{token.IDENTIFIER, 4, 18, 43, 44},
+		{token.SEMICOLON, 4, 19, 44, 45},
+		{token.EOF, 4, 20, 45, 45},
+	}
+	if len(testCases) != len(tokens) {
+		t.Fatalf("expected %d tokens but got %d", len(testCases), len(tokens))
+	}
+	for i, tt := range testCases {
+		tok := tokens[i]
+		if tok.Type != tt.expectedType || tok.Line != tt.expectedLine || tok.Column != tt.expectedColumn ||
+			tok.Offset != tt.expectedOffset || tok.EndOffset != tt.expectedEnd {
+			t.Errorf("[test %d] - expected %q at %d:%d [%d, %d) but got %q at %d:%d [%d, %d)", i,
+				tt.expectedType, tt.expectedLine, tt.expectedColumn, tt.expectedOffset, tt.expectedEnd,
+				tok.Type, tok.Line, tok.Column, tok.Offset, tok.EndOffset)
+		}
+		if tok.Type != token.EOF && source[tok.Offset:tok.EndOffset] != tok.Lexeme {
+			t.Errorf("[test %d] - expected the offsets to cover %q", i, tok.Lexeme)
+		}
+	}
+}
+
+func TestScanStringEscapes(t *testing.T) {
+	testCases := []struct {
+		source          string
+		expectedLiteral string
+	}{
+		{`"a\nb"`, "a\nb"},
+		{`"\tx\r"`, "\tx\r"},
+		{`"say \"hi\""`, `say "hi"`},
+		{`"back\\slash"`, `back\slash`},
+		{`"nul\0"`, "nul\x00"},
+		{`"\x41\x7f"`, "A\x7f"},
+		{`"\u{48}\u{e9}\u{1F600}"`, "Hé😀"},
+		{`"no escapes"`, "no escapes"},
+	}
+	for _, tt := range testCases {
+		tokens, err := NewScanner(tt.source, parseerror.NewDiagnostics()).ScanTokens()
+		if err != nil {
+			t.Errorf("%s: %s", tt.source, err)
+			continue
+		}
+		if tokens[0].Type != token.STRING || tokens[0].Literal != tt.expectedLiteral {
+			t.Errorf("%s: expected the literal %q but got %q", tt.source, tt.expectedLiteral, tokens[0].Literal)
+		}
+		if tokens[0].Lexeme != tt.source {
+			t.Errorf("%s: expected the raw lexeme to be kept but got %s", tt.source, tokens[0].Lexeme)
+		}
+	}
+}
+
+func TestScanInvalidEscapes(t *testing.T) {
+	testCases := []struct {
+		source           string
+		expectedSequence string
+		expectedSpan     token.Span
+	}{
+		{`"\q"`, `\q`, token.Span{Start: 1, End: 3}},
+		{`"ab\x4"`, `\x4`, token.Span{Start: 3, End: 6}},
+		{`"\xFF"`, `\xFF`, token.Span{Start: 1, End: 5}},
+		{`"\u41"`, `\u`, token.Span{Start: 1, End: 3}},
+		{`"\u{41"`, `\u{41`, token.Span{Start: 1, End: 6}},
+		{`"\u{}"`, `\u{}`, token.Span{Start: 1, End: 5}},
+		{`"\u{D800}"`, `\u{D800}`, token.Span{Start: 1, End: 9}},
+		{`"\u{1234567}"`, `\u{123456`, token.Span{Start: 1, End: 10}},
+	}
+	for _, tt := range testCases {
+		tokens, err := NewScanner(tt.source, parseerror.NewDiagnostics()).ScanTokens()
+		errs, ok := err.(parseerror.ErrorList)
+		if !ok || len(errs) != 1 {
+			t.Errorf("%s: expected a single error but got %v", tt.source, err)
+			continue
+		}
+		expected := InvalidEscapeError{Line: 1, Column: tt.expectedSpan.Start + 1, Span: tt.expectedSpan, Sequence: tt.expectedSequence}
+		if errs[0] != expected {
+			t.Errorf("%s: expected %#v but got %#v", tt.source, expected, errs[0])
+		}
+		if tokens[0].Type != token.STRING {
+			t.Errorf("%s: expected the string to still be scanned but got %q", tt.source, tokens[0].Type)
+		}
+	}
+}
+
+func TestScanUnicode(t *testing.T) {
+	source := "var café = \"naïve ☕\"; /* ünïcode */ π ÷"
+	tokens, err := NewScanner(source, parseerror.NewDiagnostics()).ScanTokens()
+
+	testCases := []struct {
+		expectedType   token.Type
+		expectedLexeme string
+		expectedColumn int
+	}{
+		{token.VAR, "var", 1},
+		{token.IDENTIFIER, "café", 5},
+		{token.EQUAL, "=", 10},
+		{token.STRING, "\"naïve ☕\"", 12},
+		{token.SEMICOLON, ";", 21},
+		{token.COMMENT, "/* ünïcode */", 23},
+		{token.IDENTIFIER, "π", 37},
+		{token.EOF, "", 40},
+	}
+	if len(testCases) != len(tokens) {
+		t.Fatalf("expected %d tokens but got %d", len(testCases), len(tokens))
+	}
+	for i, tt := range testCases {
+		tok := tokens[i]
+		if tok.Type != tt.expectedType || tok.Lexeme != tt.expectedLexeme || tok.Column != tt.expectedColumn {
+			t.Errorf("[test %d] - expected %q %q at column %d but got %q %q at column %d", i,
+				tt.expectedType, tt.expectedLexeme, tt.expectedColumn, tok.Type, tok.Lexeme, tok.Column)
+		}
+	}
+	if tokens[3].Literal != "naïve ☕" {
+		t.Errorf("expected the string to keep its UTF-8 but got %q", tokens[3].Literal)
+	}
+
+	expected := parseerror.ErrorList{
+		UnexpectedCharacterError{Line: 1, Column: 39, Span: token.Span{Start: 45, End: 47}, Character: "÷"},
+	}
+	if errs, ok := err.(parseerror.ErrorList); !ok || len(errs) != 1 || errs[0] != expected[0] {
+		t.Errorf("expected %#v but got %#v", expected, err)
+	}
+}
+
+func TestReaderScanner(t *testing.T) {
+	source := "var café = \"naïve ☕\\u{1F600}\";\n/* two\nlines */ print café ** 2.5; // done"
+	expected, err := NewScanner(source, parseerror.NewDiagnostics()).ScanTokens()
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+
+	// reading a byte at a time splits runes and tokens across reads
+	reader := NewReaderScanner(iotest.OneByteReader(strings.NewReader(source)), parseerror.NewDiagnostics())
+	tokens, err := reader.ScanTokens()
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	if !reflect.DeepEqual(expected, tokens) {
+		t.Errorf("expected the reader to give\n%v\nbut got\n%v", expected, tokens)
+	}
+}
+
+func TestScannerNext(t *testing.T) {
+	diagnostics := parseerror.NewDiagnostics()
+	sc := NewReaderScanner(strings.NewReader("a @ b"), diagnostics)
+
+	expected := []struct {
+		expectedType token.Type
+		expectedErr  error
+	}{
+		{token.IDENTIFIER, nil},
+		{"", UnexpectedCharacterError{Line: 1, Column: 3, Span: token.Span{Start: 2, End: 3}, Character: "@"}},
+		{token.IDENTIFIER, nil},
+		{token.EOF, nil},
+		{token.EOF, nil},
+	}
+	for i, tt := range expected {
+		tok, err := sc.Next()
+		if tok.Type != tt.expectedType || err != tt.expectedErr {
+			t.Errorf("[next %d] expected %q, %v but got %q, %v", i, tt.expectedType, tt.expectedErr, tok.Type, err)
+		}
+	}
+	if len(diagnostics.Errors()) != 1 {
+		t.Errorf("expected the error to be reported once but got %d", len(diagnostics.Errors()))
+	}
+
+	// the reader fails on its second read
+	sc = NewReaderScanner(iotest.TimeoutReader(iotest.HalfReader(strings.NewReader("print 1;"))), parseerror.NewDiagnostics())
+	_, err := sc.ScanTokens()
+	ioError := &parseerror.IOError{Err: iotest.ErrTimeout}
+	if errs, ok := err.(parseerror.ErrorList); !ok || len(errs) != 1 || !reflect.DeepEqual(errs[0], ioError) {
+		t.Errorf("expected the read error to be returned but got %v", err)
+	}
+}
+
+func TestScanNumbers(t *testing.T) {
+	testCases := []struct {
+		source        string
+		expectedValue float64
+	}{
+		{"123", 123},
+		{"1.5", 1.5},
+		{"0xFF", 255},
+		{"0Xff", 255},
+		{"0b1010", 10},
+		{"0o17", 15},
+		{"1e-9", 1e-9},
+		{"2.5E+3", 2500},
+		{"1e3", 1000},
+		{"1_000_000", 1000000},
+		{"0xDEAD_BEEF", 0xDEADBEEF},
+		{"3.141_592", 3.141592},
+		{"007", 7},
+	}
+	for _, tt := range testCases {
+		tokens, err := NewScanner(tt.source, parseerror.NewDiagnostics()).ScanTokens()
+		if err != nil {
+			t.Errorf("%s: %s", tt.source, err)
+			continue
+		}
+		if len(tokens) != 2 || tokens[0].Type != token.NUMBER || tokens[0].Literal != tt.expectedValue {
+			t.Errorf("%s: expected the number %v but got %v", tt.source, tt.expectedValue, tokens)
+		}
+	}
+}
+
+func TestScanMalformedNumbers(t *testing.T) {
+	testCases := []struct {
+		source         string
+		expectedNumber string
+		expectedReason string
+	}{
+		{"0x", "0x", "expected hexadecimal digits after '0x'"},
+		{"0b;", "0b", "expected binary digits after '0b'"},
+		{"0b102", "0b102", "unexpected '2'"},
+		{"0o8", "0o8", "expected octal digits after '0o'"},
+		{"0xFG", "0xFG", "unexpected 'G'"},
+		{"1e", "1e", "expected digits in the exponent"},
+		{"1e+;", "1e+", "expected digits in the exponent"},
+		{"1__000", "1__000", "'_' must be between digits"},
+		{"1_", "1_", "'_' must be between digits"},
+		{"1_.5", "1_.5", "'_' must be between digits"},
+		{"0x_FF", "0x_FF", "'_' must be between digits"},
+		{"12abc", "12abc", "unexpected 'a'"},
+		{"1e999", "1e999", "the number is too large"},
+		{"0x1_0000_0000_0000_0000", "0x1_0000_0000_0000_0000", "the number is too large"},
+	}
+	for _, tt := range testCases {
+		tokens, err := NewScanner(tt.source, parseerror.NewDiagnostics()).ScanTokens()
+		errs, ok := err.(parseerror.ErrorList)
+		if !ok || len(errs) != 1 {
+			t.Errorf("%s: expected a single error but got %v", tt.source, err)
+			continue
+		}
+		expected := MalformedNumberError{
+			Line:   1,
+			Column: 1,
+			Span:   token.Span{Start: 0, End: len(tt.expectedNumber)},
+			Number: tt.expectedNumber,
+			Reason: tt.expectedReason,
+		}
+		if errs[0] != expected {
+			t.Errorf("%s: expected %#v but got %#v", tt.source, expected, errs[0])
+		}
+		if tokens[0].Type != token.NUMBER || tokens[0].Literal != 0.0 {
+			t.Errorf("%s: expected the number to be scanned as 0 but got %v", tt.source, tokens[0])
+		}
+	}
+}
+
+func TestScanBlockComments(t *testing.T) {
+	testCases := []struct {
+		source          string
+		expectedLiteral string
+		expectedLine    int
+	}{
+		{"/* a * b */ x", " a * b ", 1},
+		{"/* ** / */ x", " ** / ", 1},
+		{"/* outer /* inner */ still outer */ x", " outer /* inner */ still outer ", 1},
+		{"/* 1 /* 2 /* 3 */ 2 */ 1 */ x", " 1 /* 2 /* 3 */ 2 */ 1 ", 1},
+		{"/* one\ntwo /*\nthree */\n*/ x", " one\ntwo /*\nthree */\n", 4},
+		{"/**/ x", "", 1},
+	}
+	for _, tt := range testCases {
+		tokens, err := NewScanner(tt.source, parseerror.NewDiagnostics()).ScanTokens()
+		if err != nil {
+			t.Errorf("%q: %s", tt.source, err)
+			continue
+		}
+		if len(tokens) != 3 || tokens[0].Type != token.COMMENT || tokens[1].Type != token.IDENTIFIER {
+			t.Errorf("%q: expected a comment and an identifier but got %v", tt.source, tokens)
+			continue
+		}
+		if tokens[0].Literal != tt.expectedLiteral {
+			t.Errorf("%q: expected the comment %q but got %q", tt.source, tt.expectedLiteral, tokens[0].Literal)
+		}
+		if tokens[1].Line != tt.expectedLine {
+			t.Errorf("%q: expected the identifier on line %d but got %d", tt.source, tt.expectedLine, tokens[1].Line)
+		}
+	}
+
+	// the stray */ is a multiplication and a division
+	tokens, err := NewScanner("a */ b", parseerror.NewDiagnostics()).ScanTokens()
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	if len(tokens) != 5 || tokens[1].Type != token.STAR || tokens[2].Type != token.SLASH {
+		t.Errorf("expected */ outside a comment to be * and / but got %v", tokens)
+	}
+
+	_, err = NewScanner("x /* open /* nested */ but\nnot closed", parseerror.NewDiagnostics()).ScanTokens()
+	expected := UnterminatedCommentError{Line: 1, Column: 3, Span: token.Span{Start: 2, End: 37}}
+	if errs, ok := err.(parseerror.ErrorList); !ok || len(errs) != 1 || errs[0] != expected {
+		t.Errorf("expected %#v but got %#v", expected, err)
+	}
+}
+
+func TestScanSkipComments(t *testing.T) {
+	sc := NewScanner("/* a */ print /* b /* c */ */ 1; // d", parseerror.NewDiagnostics())
+	sc.SkipComments = true
+	tokens, err := sc.ScanTokens()
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	expected := []token.Type{token.PRINT, token.NUMBER, token.SEMICOLON, token.EOF}
+	if len(tokens) != len(expected) {
+		t.Fatalf("expected %d tokens but got %v", len(expected), tokens)
+	}
+	for i, typ := range expected {
+		if tokens[i].Type != typ {
+			t.Errorf("[test %d] - wrong token Type. Expected %q, got %q", i, typ, tokens[i].Type)
+		}
+	}
+}
+
+func TestScanTrivia(t *testing.T) {
+	sc := NewScanner("  /* c */\tprint 1; // done\r\n", parseerror.NewDiagnostics())
+	sc.KeepTrivia = true
+	tokens, err := sc.ScanTokens()
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+
+	expected := []struct {
+		expectedType   token.Type
+		expectedTrivia []token.Trivia
+	}{
+		{token.PRINT, []token.Trivia{
+			{Kind: token.WHITESPACE, Text: "  "},
+			{Kind: token.BLOCKCOMMENT, Text: "/* c */"},
+			{Kind: token.WHITESPACE, Text: "\t"},
+		}},
+		{token.NUMBER, []token.Trivia{{Kind: token.WHITESPACE, Text: " "}}},
+		{token.SEMICOLON, nil},
+		{token.EOF, []token.Trivia{
+			{Kind: token.WHITESPACE, Text: " "},
+			{Kind: token.LINECOMMENT, Text: "// done\r"},
+			{Kind: token.NEWLINE, Text: "\n"},
+		}},
+	}
+	if len(tokens) != len(expected) {
+		t.Fatalf("expected %d tokens but got %v", len(expected), tokens)
+	}
+	for i, tt := range expected {
+		if tokens[i].Type != tt.expectedType || !reflect.DeepEqual(tokens[i].LeadingTrivia, tt.expectedTrivia) {
+			t.Errorf("[test %d] - expected %q after %v but got %q after %v", i,
+				tt.expectedType, tt.expectedTrivia, tokens[i].Type, tokens[i].LeadingTrivia)
+		}
+	}
+}
+
+func TestTriviaRoundTrip(t *testing.T) {
+	files, err := filepath.Glob("../examples/*.lo")
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	if len(files) == 0 {
+		t.Fatalf("expected example files to round trip")
+	}
+	sources := map[string]string{
+		"errors":  "var a = @ 1;\n\"bad \\q\" 0x; \"open",
+		"unicode": "var café = \"☕\";\r\n\t/* a /* b */ */ print café; // ünï",
+		"open":    "print 1; /* never closed\n",
+	}
+	for _, file := range files {
+		data, err := ioutil.ReadFile(file)
+		if err != nil {
+			t.Fatalf("%s", err)
+		}
+		sources[file] = string(data)
+	}
+
+	for name, source := range sources {
+		scanners := []*Scanner{
+			NewScanner(source, parseerror.NewDiagnostics()),
+			NewReaderScanner(iotest.OneByteReader(strings.NewReader(source)), parseerror.NewDiagnostics()),
+		}
+		for _, sc := range scanners {
+			sc.KeepTrivia = true
+			tokens, _ := sc.ScanTokens()
+			var text strings.Builder
+			for _, tok := range tokens {
+				if tok.Type == token.COMMENT {
+					t.Errorf("%s: expected comments to be trivia", name)
+				}
+				text.WriteString(tok.FullText())
+			}
+			if text.String() != source {
+				t.Errorf("%s: expected the tokens to give back\n%q\nbut got\n%q", name, source, text.String())
+			}
+		}
+	}
+}
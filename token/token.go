@@ -1,6 +1,9 @@
 package token
 
-import "fmt"
+import (
+	"fmt"
+	"strings"
+)
 
 // Type is the kind of token given as a string
 type Type string
@@ -59,14 +62,69 @@ const (
 	INVALID  = "__INVALID__"
 )
 
-//Token contains the lexeme read by the scanner
+//Token contains the lexeme read by the scanner. Line and Column are
+// where the token starts while Offset and EndOffset are the byte offsets
+// of the start and just past the end of the lexeme in the source
 type Token struct {
-	Type    Type
-	Lexeme  string
-	Literal interface{}
-	Line    int
+	Type      Type
+	Lexeme    string
+	Literal   interface{}
+	Line      int
+	Column    int
+	Offset    int
+	EndOffset int
+	// LeadingTrivia is the text between the previous token and this one.
+	// It is only kept when scanning losslessly
+	LeadingTrivia []Trivia
 }
 
 func (token *Token) String() string {
 	return fmt.Sprintf("%s %s %v", token.Type, token.Lexeme, token.Literal)
 }
+
+// FullText is the source text of the token along with its leading trivia
+func (token *Token) FullText() string {
+	var sb strings.Builder
+	for _, trivia := range token.LeadingTrivia {
+		sb.WriteString(trivia.Text)
+	}
+	sb.WriteString(token.Lexeme)
+	return sb.String()
+}
+
+// TriviaKind is the kind of Trivia given as a string
+type TriviaKind string
+
+// kinds of Trivia
+const (
+	WHITESPACE   TriviaKind = "WHITESPACE"
+	NEWLINE      TriviaKind = "NEWLINE"
+	LINECOMMENT  TriviaKind = "LINE_COMMENT"
+	BLOCKCOMMENT TriviaKind = "BLOCK_COMMENT"
+	// SKIPPED is text the scanner could not make a token of
+	SKIPPED TriviaKind = "SKIPPED"
+)
+
+// Trivia is source text that means nothing to the parser, such as spaces
+// and comments, kept so that tools can reproduce the source exactly
+type Trivia struct {
+	Kind TriviaKind
+	Text string
+}
+
+// Span is a range of source given as the byte offset of its start and the
+// byte offset just past its end
+type Span struct {
+	Start int
+	End   int
+}
+
+// SpanOf returns the Span the token covers
+func SpanOf(t Token) Span {
+	return Span{Start: t.Offset, End: t.EndOffset}
+}
+
+// Join returns the Span from the start of s to the end of other
+func (s Span) Join(other Span) Span {
+	return Span{Start: s.Start, End: other.End}
+}
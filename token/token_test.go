@@ -7,18 +7,18 @@ import (
 
 func TestToken(t *testing.T) {
 	tokens := []Token{
-		{NUMBER, "2", nil, 1},
-		{PLUS, "+", nil, 2},
-		{NUMBER, "2", nil, 3},
-		{EQUAL, "=", nil, 4},
-		{NUMBER, "4", nil, 5},
+		{Type: NUMBER, Lexeme: "2", Line: 1, Column: 1, Offset: 0, EndOffset: 1},
+		{Type: PLUS, Lexeme: "+", Line: 2, Column: 1, Offset: 2, EndOffset: 3},
+		{Type: NUMBER, Lexeme: "2", Line: 3, Column: 1, Offset: 4, EndOffset: 5},
+		{Type: EQUAL, Lexeme: "=", Line: 4, Column: 1, Offset: 6, EndOffset: 7},
+		{Type: NUMBER, Lexeme: "4", Line: 5, Column: 1, Offset: 8, EndOffset: 9},
 	}
 	strTokens := []string{
-		"{NUMBER 2 <nil> 1}",
-		"{+ + <nil> 2}",
-		"{NUMBER 2 <nil> 3}",
-		"{= = <nil> 4}",
-		"{NUMBER 4 <nil> 5}",
+		"{NUMBER 2 <nil> 1 1 0 1 []}",
+		"{+ + <nil> 2 1 2 3 []}",
+		"{NUMBER 2 <nil> 3 1 4 5 []}",
+		"{= = <nil> 4 1 6 7 []}",
+		"{NUMBER 4 <nil> 5 1 8 9 []}",
 	}
 	for i, token := range tokens {
 		strToken := fmt.Sprint(token)
@@ -27,3 +27,23 @@ func TestToken(t *testing.T) {
 		}
 	}
 }
+
+func TestSpan(t *testing.T) {
+	left := Token{Type: IDENTIFIER, Lexeme: "a", Line: 1, Column: 1, Offset: 0, EndOffset: 1}
+	right := Token{Type: IDENTIFIER, Lexeme: "bc", Line: 1, Column: 5, Offset: 4, EndOffset: 6}
+	span := SpanOf(left).Join(SpanOf(right))
+	if span != (Span{Start: 0, End: 6}) {
+		t.Errorf("expected the span 0-6 but got %v", span)
+	}
+}
+
+func TestFullText(t *testing.T) {
+	tok := Token{Type: VAR, Lexeme: "var", LeadingTrivia: []Trivia{
+		{Kind: NEWLINE, Text: "\n"},
+		{Kind: BLOCKCOMMENT, Text: "/* x */"},
+		{Kind: WHITESPACE, Text: " \t"},
+	}}
+	if got := tok.FullText(); got != "\n/* x */ \tvar" {
+		t.Errorf("expected the trivia before the lexeme but got %q", got)
+	}
+}